@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"sync"
+	"sync/atomic"
 	"syscall/js"
 	"time"
 
@@ -25,14 +26,26 @@ func (f OnClickFunc) OnClick() {
 	f()
 }
 
-var application *app
+// The default application, used by the package level functions.
+var application *App
 
 type lastTemplate struct {
 	name string
 	fun  func(args ...interface{}) Marshaller
 }
 
-type app struct {
+// App is an application mounted to a root element.
+//
+// Each application has its own mux, logger, messenger, templates, hooks, tasks and sockets,
+// this allows multiple applications to be embedded on the same page.
+//
+// The package level functions operate on the default application, created with New.
+//
+// An application handles the links clicked inside of its root element, the default application
+// also handles links outside of the root elements of other applications.
+// Applications embedded next to each other should set F_NO_HISTORY, so they keep track of
+// their own path, instead of sharing the browser's location.
+type App struct {
 	*jse.Element     `jsc:"rootElement"`
 	elementEmbedFunc func(ctx context.Context, page *jse.Element) *jse.Element `jsc:"-"`
 	templates        map[string]func(args ...interface{}) Marshaller           `jsc:"-"`
@@ -41,12 +54,37 @@ type app struct {
 	config           *Config                                                   `jsc:"-"`
 	exit             chan error                                                `jsc:"-"`
 	globalFuncs      map[string]func(args ...interface{}) Marshaller           `jsc:"-"`
+	globalJSFuncs    map[string]js.Func                                        `jsc:"-"`
+	globalKeys       map[string]struct{}                                       `jsc:"-"`
+	onShutdown       []func(ctx context.Context) error                         `jsc:"-"`
+	errorPage        PageFunc                                                  `jsc:"-"`
+	closed           atomic.Bool                                               `jsc:"-"`
+	middleware       []Middleware                                              `jsc:"-"`
+	leaveGuards      []NavigationGuard                                         `jsc:"-"`
+	enterGuards      []NavigationGuard                                         `jsc:"-"`
+	page             *Page                                                     `jsc:"-"`
+	pending          *Page                                                     `jsc:"-"`
+	started          uint64                                                    `jsc:"-"`
+	currentPath      string                                                    `jsc:"-"`
+	clickFunc        js.Func                                                   `jsc:"-"`
+	popStateFunc     js.Func                                                   `jsc:"-"`
+	historyIndex     int                                                       `jsc:"-"`
+	skipPop          bool                                                      `jsc:"-"`
+	popRedirect      string                                                    `jsc:"-"`
+	navigations      uint64                                                    `jsc:"-"`
+	sockets          map[string]*namedSock                                     `jsc:"-"`
+	socketsMut       sync.Mutex                                                `jsc:"-"`
+	socks            []*websocket.WebSocket                                    `jsc:"-"`
+	eventSources     []*EventSource                                            `jsc:"-"`
+	socksMut         sync.Mutex                                                `jsc:"-"`
 	Mux              *mux.Mux                                                  `jsc:"-"`
 	Loader           Loader                                                    `jsc:"-"`
 	Logger           Logger                                                    `jsc:"-"`
 	OnResponseError  func(error)                                               `jsc:"-"`
 	Messenger        Messenger                                                 `jsc:"-"`
 	Websocket        *websocket.WebSocket                                      `jsc:"-"`
+	EventSource      *EventSource                                              `jsc:"-"`
+	ManagedSocket    *ManagedSocket                                            `jsc:"-"`
 	Tasks            tasker.Tasker                                             `jsc:"-"`
 	Data             map[string]interface{}                                    `jsc:"-"`
 	Client           *craterhttp.Client                                        `jsc:"-"`
@@ -59,96 +97,165 @@ func checkApp() {
 	}
 }
 
+// Returns a if it is not nil, otherwise the default application.
+//
+// The result is nil if a is nil and the default application is not initialized.
+func appOr(a *App) *App {
+	if a != nil {
+		return a
+	}
+	return application
+}
+
 // Helper function to check if an error is nil, if not, log it and call the OnResponseError function
-func checkErr(err error) bool {
+func (a *App) checkErr(err error) bool {
 	if err == nil {
 		return false
 	}
-	LogError(fmt.Sprintf("Error: %s", err.Error()))
-	if application.OnResponseError != nil {
-		application.OnResponseError(err)
+	a.LogError(fmt.Sprintf("Error: %s", err.Error()))
+	if a.OnResponseError != nil {
+		a.OnResponseError(err)
 	} else {
 		panic(err)
 	}
 	return true
 }
 
-// Initialize a new application
+// Helper function to report an error without panicking.
+//
+// The error is logged, and passed to the OnResponseError function if it is set.
+func (a *App) reportErr(err error) {
+	a.LogError(fmt.Sprintf("Error: %s", err.Error()))
+	if a.OnResponseError != nil {
+		a.OnResponseError(err)
+	}
+}
+
+// Initialize the default application
 //
 // The config parameter is optional, if nil, the default config will be used
 func New(c *Config) {
 	if application != nil {
 		panic("Application already initialized")
 	}
+	application = NewApp(c)
+}
+
+// Default returns the default application, or nil if New has not been called.
+func Default() *App {
+	return application
+}
+
+// Create a new application, independent of the default application.
+//
+// The config parameter is optional, if nil, the default config will be used.
+// Applications which are embedded on the same page should each have their own root element.
+func NewApp(c *Config) *App {
 	if c == nil {
 		c = &Config{
 			RootElement: jsext.Body,
 		}
 	}
-	application = &app{
+	var a = &App{
 		Mux:              mux.New(),
 		Element:          (*jse.Element)(&c.RootElement),
-		exit:             make(chan error),
+		exit:             make(chan error, 1),
 		signals:          signals.NewPool[any](),
 		config:           c,
 		globalFuncs:      make(map[string]func(args ...interface{}) Marshaller),
+		globalJSFuncs:    make(map[string]js.Func),
+		globalKeys:       make(map[string]struct{}),
 		Loader:           c.Loader,
 		Messenger:        c.Messenger,
 		Logger:           c.Logger,
 		OnResponseError:  c.OnResponseError,
 		elementEmbedFunc: c.EmbedFunc,
 		templates:        c.Templates,
+		middleware:       c.Middleware,
+		errorPage:        c.ErrorPage,
+		sockets:          make(map[string]*namedSock),
 		Tasks:            tasker.New(),
 		Data:             make(map[string]interface{}),
-		Client:           craterhttp.NewClient(c.HttpClientTimeout),
 	}
+	a.Client = a.newClient(c.HttpClientTimeout)
 
-	application.Mux.InvokeHandler(c.Flags.Has(F_CHANGE_PAGE_EACH_CLICK))
-	if c.InitialPageURL != "" {
-		application.Mux.FirstPage(c.InitialPageURL)
-	}
 	if c.NotFoundHandler != nil {
-		application.Mux.NotFoundHandler = makeHandleFunc(c.NotFoundHandler)
+		a.Mux.NotFoundHandler = a.makeHandleFunc(c.NotFoundHandler, nil)
 	}
+	return a
 }
 
 // Enqueue a task periodically by name.
 // If there is an error, it will be of type:
 // - ErrNoNameSpecified
+func (a *App) Enqueue(task tasker.Task) error {
+	return a.Tasks.Enqueue(task)
+}
+
+// Enqueue a task on the default application, see App.Enqueue.
 func Enqueue(task tasker.Task) error {
 	checkApp()
-	return application.Tasks.Enqueue(task)
+	return application.Enqueue(task)
 }
 
 // Execute a task after the duration has passed, or immediately if the duration is 0.
 // If the task name is provided, the task will be reset to the new duration.
 // If the task name is not provided, the task will be executed once after the duration has passed.
+func (a *App) After(task tasker.Task) error {
+	return a.Tasks.After(task)
+}
+
+// Execute a task on the default application, see App.After.
 func After(task tasker.Task) error {
 	checkApp()
-	return application.Tasks.After(task)
+	return application.After(task)
 }
 
 // Dequeue a task by name.
 // If there is an error, it will be of types:
 // - ErrNoNameSpecified
 // - ErrNotFound
+func (a *App) Dequeue(task string) error {
+	return a.Tasks.Dequeue(task)
+}
+
+// Dequeue a task from the default application, see App.Dequeue.
 func Dequeue(task string) error {
 	checkApp()
-	return application.Tasks.Dequeue(task)
+	return application.Dequeue(task)
 }
 
-// Client returns the application's http client.
+// Client returns the default application's http client.
+//
+// The client of other applications is available as App.Client.
 func Client() *craterhttp.Client {
 	checkApp()
 	if application.Client == nil {
-		application.Client = craterhttp.NewClient(application.config.HttpClientTimeout)
-		application.Client.OnResponse = func(r *craterhttp.Response) error {
-			return application.signals.CreateOrSend(SignalClientResponse, application.Client)
-		}
+		application.Client = application.newClient(application.config.HttpClientTimeout)
 	}
 	return application.Client
 }
 
+// Returns the application's http client, creating it if it was unset.
+func (a *App) client() *craterhttp.Client {
+	if a.Client == nil {
+		a.Client = a.newClient(a.config.HttpClientTimeout)
+	}
+	return a.Client
+}
+
+// Create a new http client which sends SignalClientResponse for each response.
+func (a *App) newClient(timeout time.Duration) *craterhttp.Client {
+	var client = craterhttp.NewClient(timeout)
+	client.UseResponse(func(r *craterhttp.Request, resp *craterhttp.Response, err error) (*craterhttp.Response, error) {
+		if err != nil {
+			return resp, err
+		}
+		return resp, a.signals.CreateOrSend(SignalClientResponse, client)
+	})
+	return client
+}
+
 type SockOpts struct {
 	Protocols []string
 	OnOpen    func(*websocket.WebSocket, websocket.MessageEvent)
@@ -159,7 +266,7 @@ type SockOpts struct {
 
 func (o *SockOpts) OpenSock(url string) *websocket.WebSocket {
 	var sock *websocket.WebSocket
-	if o != nil || len(o.Protocols) > 0 {
+	if o != nil && len(o.Protocols) > 0 {
 		sock = websocket.New(url, o.Protocols...)
 	} else {
 		sock = websocket.New(url)
@@ -183,97 +290,169 @@ func (o *SockOpts) Apply(sock *websocket.WebSocket) {
 }
 
 // Open a websocket for the application.
-func OpenSock(url string, options *SockOpts) {
-	checkApp()
-
-	if application.Websocket == nil || !application.Websocket.IsOpen() {
-		application.Websocket = options.OpenSock(url)
+func (a *App) OpenSock(url string, options *SockOpts) {
+	if a.Websocket == nil || !a.Websocket.IsOpen() {
+		a.Websocket = options.OpenSock(url)
 	}
 
 	if options == nil {
 		return
 	}
 
-	if err := application.signals.CreateOrSend(SignalSockConnected, application.Websocket); err != nil {
+	if err := a.signals.CreateOrSend(SignalSockConnected, a.Websocket); err != nil {
 		return
 	}
 
-	options.Apply(application.Websocket)
+	options.Apply(a.Websocket)
+
+}
 
+// Open a websocket for the default application, see App.OpenSock.
+func OpenSock(url string, options *SockOpts) {
+	checkApp()
+	application.OpenSock(url, options)
 }
 
-// Retrieve the application's path multiplexer.
+// Retrieve the default application's path multiplexer.
+//
+// The mux holds the routes, link clicks and history changes are handled by the application itself.
+// The mux of other applications is available as App.Mux.
 func Mux() *mux.Mux {
 	checkApp()
 	return application.Mux
 }
 
 // Retrieve the application's root element.
+func (a *App) Canvas() *jse.Element {
+	return a.Element
+}
+
+// Retrieve the default application's root element.
 func Canvas() *jse.Element {
 	checkApp()
-	return application.Element
+	return application.Canvas()
 }
 
 // Socket returns the application's websocket.
+func (a *App) Socket() *websocket.WebSocket {
+	return a.Websocket
+}
+
+// Socket returns the default application's websocket.
 func Socket() *websocket.WebSocket {
 	checkApp()
-	return application.Websocket
+	return application.Socket()
 }
 
 // Exit the application with an error.
+//
+// Exit does not block, if the application is already exiting the error is dropped.
+func (a *App) Exit(err error) {
+	select {
+	case a.exit <- err:
+	default:
+	}
+}
+
+// Exit the default application with an error.
 func Exit(err error) {
 	checkApp()
-	application.exit <- err
+	application.Exit(err)
 }
 
 // RegisterHook registers a hook with the application.
-func RegisterHook(name string, hook func(any) error) {
-	checkApp()
-	application.signals.Listen(name, func(_ signals.Signal[any], v any) error {
+func (a *App) RegisterHook(name string, hook func(any) error) {
+	a.signals.Listen(name, func(_ signals.Signal[any], v any) error {
 		return hook(v)
 	})
 }
 
+// RegisterHook registers a hook with the default application.
+func RegisterHook(name string, hook func(any) error) {
+	checkApp()
+	application.RegisterHook(name, hook)
+}
+
 // Send a signal through the application's hook system.
+func (a *App) SendHook(name string, v any) error {
+	return a.signals.CreateOrSend(name, v)
+}
+
+// Send a signal through the default application's hook system.
 func SendHook(name string, v any) error {
 	checkApp()
-	return application.signals.CreateOrSend(name, v)
+	return application.SendHook(name, v)
 }
 
 // Run the application.
 //
 // This function will block until the application exits.
-func Run() error {
-	checkApp()
-
-	if err := application.signals.CreateOrSend(SignalRun, nil); err != nil {
+func (a *App) Run() error {
+	if err := a.signals.CreateOrSend(SignalRun, nil); err != nil {
 		return nil
 	}
 
-	application.Mux.ListenForChanges()
+	a.listen()
 
-	var exit = <-application.exit
-	if err := application.signals.CreateOrSend(SignalExit, exit); err != nil {
+	var exit = <-a.exit
+	if err := a.signals.CreateOrSend(SignalExit, exit); err != nil {
 		return nil
 	}
 	return exit
 }
 
+// Run the default application.
+//
+// This function will block until the application exits.
+func Run() error {
+	checkApp()
+	return application.Run()
+}
+
 // Change page to the given path.
+//
+// The path is pushed to the browser's history, unless F_NO_HISTORY is set.
+func (a *App) HandlePath(path string) {
+	a.navigate(path, historyPush)
+}
+
+// Change the page of the default application to the given path.
 func HandlePath(path string) {
 	checkApp()
-	application.Mux.HandlePath(path)
+	application.HandlePath(path)
+}
+
+// Redirect is a wrapper around HandlePath.
+func (a *App) Redirect(path string) {
+	a.HandlePath(path)
 }
 
 // Redirect is a wrapper around HandlePath.
 func Redirect(path string) {
 	checkApp()
-	HandlePath(path)
+	application.Redirect(path)
+}
+
+// Use adds middleware to the application.
+//
+// The middleware will be applied to every page, before any route-level middleware.
+func (a *App) Use(m ...Middleware) {
+	a.middleware = append(a.middleware, m...)
+}
+
+// Use adds middleware to the default application, see App.Use.
+func Use(m ...Middleware) {
+	checkApp()
+	application.Use(m...)
 }
 
 // The route used to handle child routes, and handle pages.
 type route struct {
-	r *mux.Route
+	r           *mux.Route
+	app         *App
+	parent      *route
+	middleware  []Middleware
+	enterGuards []NavigationGuard
 }
 
 // Handle a path with a page function.
@@ -282,11 +461,44 @@ type route struct {
 //
 // This function returns a route that can be used to add children.
 func (r *route) Handle(path string, h PageFunc) Route {
-	checkApp()
-	var rt = r.r.Handle(path, makeHandleFunc(h))
-	return &route{
-		r: rt,
+	var rt = &route{
+		app:    r.app,
+		parent: r,
+	}
+	rt.r = r.r.Handle(path, r.app.makeHandleFunc(h, rt))
+	return rt
+}
+
+// Use adds middleware to the route.
+//
+// The middleware will also be applied to all child routes.
+func (r *route) Use(m ...Middleware) Route {
+	r.middleware = append(r.middleware, m...)
+	return r
+}
+
+// BeforeEnter adds navigation guards to the route.
+//
+// The guards will also be applied to all child routes.
+func (r *route) BeforeEnter(g ...NavigationGuard) Route {
+	r.enterGuards = append(r.enterGuards, g...)
+	return r
+}
+
+// Returns the navigation guards for this route, starting with the root route's guards.
+func (r *route) guards() []NavigationGuard {
+	if r == nil {
+		return nil
+	}
+	return append(r.parent.guards(), r.enterGuards...)
+}
+
+// Returns the middleware for this route, starting with the root route's middleware.
+func (r *route) chain() []Middleware {
+	if r == nil {
+		return nil
 	}
+	return append(r.parent.chain(), r.middleware...)
 }
 
 // Handle a path with a page function.
@@ -294,21 +506,32 @@ func (r *route) Handle(path string, h PageFunc) Route {
 // The page function will be called when the path is visited.
 //
 // This function returns a route that can be used to add children.
+func (a *App) Handle(path string, h PageFunc) Route {
+	var rt = &route{
+		app: a,
+	}
+	rt.r = a.Mux.Handle(path, a.makeHandleFunc(h, rt))
+	return rt
+}
+
+// Handle a path with a page function on the default application, see App.Handle.
 func Handle(path string, h PageFunc) Route {
 	checkApp()
-	var rt = application.Mux.Handle(path, makeHandleFunc(h))
-	return &route{
-		r: rt,
-	}
+	return application.Handle(path, h)
 }
 
-var (
-	// global websocket connections
-	socks    = make([]*websocket.WebSocket, 0)
-	socksMut = new(sync.Mutex)
-)
+// Wrap the page function in the application's middleware, followed by the route's middleware.
+//
+// The first middleware added will be the first one to run.
+func (a *App) applyMiddleware(h PageFunc, rt *route) PageFunc {
+	var middleware = append(append([]Middleware{}, a.middleware...), rt.chain()...)
+	for i := len(middleware) - 1; i >= 0; i-- {
+		h = middleware[i](h)
+	}
+	return h
+}
 
-func makeHandleFunc(h PageFunc) mux.Handler {
+func (a *App) makeHandleFunc(h PageFunc, rt *route) mux.Handler {
 
 	if h == nil {
 		panic("HandleFunc cannot be nil")
@@ -324,42 +547,101 @@ func makeHandleFunc(h PageFunc) mux.Handler {
 	// Templates for the handler.
 	if templater, ok := h.(Templater); ok {
 		for k, v := range templater.Templates() {
-			SetTemplate(k, v)
+			a.SetTemplate(k, v)
 		}
 	}
 
 	// Hooks for the handler.
-	if err := application.signals.CreateOrSend(SignalHandlerAdded, h); err != nil {
+	if err := a.signals.CreateOrSend(SignalHandlerAdded, h); err != nil {
 		return nil
 	}
 
 	// Websocket for this specific handler.
 	var ws *websocket.WebSocket
 
-	return mux.NewHandler(func(v mux.Variables) {
-		// The context of the page.
-		var ctx = context.Background()
+	// Event source for this specific handler.
+	var es *EventSource
+
+	return &pageHandler{serve: func(v mux.Variables, req navigationRequest) {
+		// The application was shut down while the navigation was pending.
+		if a.closed.Load() {
+			return
+		}
+
+		// Run the navigation guards, the previous page is left intact if the navigation is cancelled.
+		var nav = &Navigation{
+			ID:          atomic.AddUint64(&a.navigations, 1),
+			To:          variablesPath(v),
+			ToVariables: v,
+			request:     req,
+		}
+
+		// Serve the error page if anything panics while the page is served.
+		var page *Page
+		defer func() {
+			if rec := recover(); rec == nil {
+				return
+			} else if a.stale(nav, page) {
+				a.LogErrorf("Recovered from panic while serving %s, page was replaced: %v", nav.To, rec)
+				a.finishPending(page, true)
+			} else {
+				a.finishPending(page, false)
+				a.recoverPage(rec, nav, page)
+			}
+		}()
+
+		if a.page != nil {
+			nav.From = a.page.uri
+			nav.FromVariables = a.page.Variables
+		}
+		if !a.runGuards(nav, rt) {
+			return
+		}
+
 		// Hooks for the handler.
-		if err := application.signals.CreateOrSend(SignalPageChange, nil); err != nil {
+		if err := a.signals.CreateOrSend(SignalPageChange, nav); err != nil {
 			return
 		}
 
+		// The page which is still loading is replaced, cancel its context so it is not rendered.
+		a.started = nav.ID
+		if pending := a.pending; pending != nil {
+			a.pending = nil
+			pending.cancel()
+		}
+
+		// Tear down the previous page before the next one is rendered.
+		//
+		// This also cancels the context of the previous page.
+		if prev := a.page; prev != nil {
+			a.page = nil
+			prev.destroy()
+		}
+
+		// The context of the page.
+		var ctx, cancel = newPageContext(nav)
+
 		// Clear the application's root element.
-		application.Element.InnerHTML("")
+		a.Element.InnerHTML("")
 
 		// Close all open sockets if the flag is set.
 		//
 		// However, do not close the global application's websocket.
-		if application.config.Flags.Has(F_CLOSE_SOCKS_EACH_PAGE) {
-			socksMut.Lock()
-			for _, sock := range socks {
+		if a.config.Flags.Has(F_CLOSE_SOCKS_EACH_PAGE) {
+			a.socksMut.Lock()
+			for _, sock := range a.socks {
 				if sock != nil && sock.IsOpen() {
 					sock.Close(1000)
 				}
 			}
-			socks = make([]*websocket.WebSocket, 0)
-			socksMut.Unlock()
+			a.socks = make([]*websocket.WebSocket, 0)
+			for _, source := range a.eventSources {
+				source.Close()
+			}
+			a.eventSources = make([]*EventSource, 0)
+			a.socksMut.Unlock()
 			ws = nil
+			es = nil
 		}
 
 		// If SockConfigurator is implemented, open a socket with the given options.
@@ -369,44 +651,75 @@ func makeHandleFunc(h PageFunc) mux.Handler {
 			var url, sockOpts = wsOpts.SockOptions()
 			ws = sockOpts.OpenSock(url)
 			sockOpts.Apply(ws)
-			if application.config.Flags.Has(F_CLOSE_SOCKS_EACH_PAGE) {
-				socks = append(socks, ws)
-			}
+			a.socksMut.Lock()
+			a.socks = append(a.socks, ws)
+			a.socksMut.Unlock()
+		}
+
+		// If EventSourceConfigurator is implemented, open an event source with the given options.
+		//
+		// This will run each time the page is visited && es is nil.
+		if esOpts, ok := h.(EventSourceConfigurator); ok && es == nil {
+			var url, sourceOpts = esOpts.EventSourceOptions()
+			es = newEventSource(a, url, &sourceOpts)
+			a.socksMut.Lock()
+			a.eventSources = append(a.eventSources, es)
+			a.socksMut.Unlock()
 		}
 
 		// Set up the page.
 		var canvas *jse.Element = jse.Div("crater-canvas")
-		var page = &Page{
-			Canvas:    canvas,
-			Variables: v,
-			Context:   ctx,
-			State:     state.New(canvas.MarshalJS()),
-			Sock:      ws,
+		page = &Page{
+			Canvas:      canvas,
+			Variables:   v,
+			Context:     ctx,
+			State:       state.New(canvas.MarshalJS()),
+			Sock:        ws,
+			EventSource: es,
+			app:         a,
+			path:        nav.To,
+			uri:         req.uri,
+			handler:     h,
+			cancel:      cancel,
 		}
+		a.pending = page
 
-		// Initialization functions which will run each time the page is visited.
-		if preloader, ok := h.(Preloader); ok {
-			preloader.Preload(page)
+		// Serve the page through the middleware chain, this will render elements onto the canvas.
+		a.applyMiddleware(ToPageFunc(func(p *Page) {
+			// Initialization functions which will run each time the page is visited.
+			if preloader, ok := h.(Preloader); ok {
+				preloader.Preload(p)
+			}
+			h.Serve(p)
+		}), rt).Serve(page)
+
+		// Another navigation started while the page was loading, do not render the page.
+		if a.stale(nav, page) {
+			a.LogDebugf("Discarding page %s, page was replaced", nav.To)
+			a.finishPending(page, true)
+			return
 		}
+		a.finishPending(page, false)
 
-		// Serve the page, this will render elements onto the canvas.
-		h.Serve(page)
+		// A middleware or the page itself requested a redirect, do not render the page.
+		if page.redirect != "" {
+			page.destroy()
+			a.HandlePath(page.redirect)
+			return
+		}
 
 		// Embed if needed.
 		//
 		// Pass context of the page to support logic based embedding.
-		if application.elementEmbedFunc != nil {
-			canvas = application.elementEmbedFunc(page.Context, canvas)
+		if a.elementEmbedFunc != nil {
+			canvas = a.elementEmbedFunc(page.Context, canvas)
 		}
 
-		// If the node is a body element we cannot replace it, so we will just append the canvas.
-		if application.Element.Get("nodeName").String() == "BODY" || application.config.Flags.Has(F_APPEND_CANVAS) {
-			application.Element.InnerHTML("")
-			application.Element.AppendChild(canvas)
-		} else {
-			// Replace the application's root element with the canvas.
-			application.Element.Replace(canvas)
-		}
+		a.mount(canvas)
+
+		// The page is now the current page, its leave guards will run on the next navigation.
+		a.page = page
+		page.rendered = true
 
 		// After render functions which will run
 		// each time the page is visited and the serve function returns.
@@ -415,10 +728,31 @@ func makeHandleFunc(h PageFunc) mux.Handler {
 		}
 
 		// Hooks for the handler.
-		if err := application.signals.CreateOrSend(SignalPageRendered, page); err != nil {
+		if err := a.signals.CreateOrSend(SignalPageRendered, page); err != nil {
 			return
 		}
-	})
+	}}
+}
+
+// Reports whether a newer navigation started while the page of the navigation was loading.
+func (a *App) stale(nav *Navigation, page *Page) bool {
+	if page != nil && page.Context.Err() != nil {
+		return true
+	}
+	return nav.ID < a.started
+}
+
+// The page is no longer loading, it is destroyed if it will not be rendered.
+func (a *App) finishPending(page *Page, destroy bool) {
+	if page == nil {
+		return
+	}
+	if a.pending == page {
+		a.pending = nil
+	}
+	if destroy {
+		page.destroy()
+	}
 }
 
 // Handle a path with a page function.
@@ -426,184 +760,278 @@ func makeHandleFunc(h PageFunc) mux.Handler {
 // The page passed to this function will have acess to page.DecodeResponse and page.Response fields.
 //
 // The page function will be called when the path is visited.
-func HandleEndpoint(path string, r craterhttp.RequestFunc, h PageFunc) {
-	checkApp()
-	LogDebugf("Adding handler for path: %s", path)
-	Handle(path, ToPageFunc(func(p *Page) {
+//
+// This function returns a route that can be used to add middleware, guards and children.
+func (a *App) HandleEndpoint(path string, r craterhttp.RequestFunc, h PageFunc) Route {
+	a.LogDebugf("Adding handler for path: %s", path)
+	return a.Handle(path, ToPageFunc(func(p *Page) {
 		var (
 			request *craterhttp.Request
 			err     error
 		)
-		LogInfof("Handling endpoint: %s", path)
-		ShowLoader()
+		a.LogInfof("Handling endpoint: %s", path)
+		a.ShowLoader()
 		request, err = r(p.Variables)
-		if checkErr(err) {
-			HideLoader()
+		if a.checkErr(err) {
+			a.HideLoader()
 			return
 		}
-		LogDebugf("Making fetch request to %s", request.URL)
-		p.Response, err = Client().Do(request)
-		if checkErr(err) {
-			HideLoader()
+		// Abort the request when the user navigates away from the page.
+		request.SetContext(p.Context)
+		a.LogDebugf("Making fetch request to %s", request.URL)
+		p.Response, err = a.client().Do(request)
+		if p.Context.Err() != nil {
+			a.LogDebugf("Fetch request to %s aborted, page was replaced", request.URL)
+			a.HideLoader()
 			return
 		}
-		HideLoader()
-		LogDebug("Received fetch response...")
+		if a.checkErr(err) {
+			a.HideLoader()
+			return
+		}
+		a.HideLoader()
+		a.LogDebug("Received fetch response...")
 		h.Serve(p)
 	}))
 }
 
+// Handle a path with a page function on the default application, see App.HandleEndpoint.
+func HandleEndpoint(path string, r craterhttp.RequestFunc, h PageFunc) Route {
+	checkApp()
+	return application.HandleEndpoint(path, r, h)
+}
+
 // Show the application's loader.
+func (a *App) ShowLoader() {
+	if a.Loader != nil {
+		a.LogDebug("Showing loader...")
+		a.Loader.Show()
+	}
+}
+
+// Show the default application's loader.
 func ShowLoader() {
 	checkApp()
-	if application.Loader != nil {
-		LogDebug("Showing loader...")
-		application.Loader.Show()
-	}
+	application.ShowLoader()
 }
 
 // Hide the application's loader.
+func (a *App) HideLoader() {
+	if a.Loader != nil {
+		a.LogDebug("Hiding loader...")
+		a.Loader.Hide()
+	}
+}
+
+// Hide the default application's loader.
 func HideLoader() {
 	checkApp()
-	if application.Loader != nil {
-		LogDebug("Hiding loader...")
-		application.Loader.Hide()
-	}
+	application.HideLoader()
 }
 
 // Set the application's log level.
+func (a *App) SetLogLevel(level logger.LogLevel) {
+	if a.Logger != nil {
+		a.LogDebugf("Setting log level to: %s", level)
+		a.Logger.Loglevel(level)
+	}
+}
+
+// Set the default application's log level.
 func SetLogLevel(level logger.LogLevel) {
 	checkApp()
-	if application.Logger != nil {
-		LogDebugf("Setting log level to: %s", level)
-		application.Logger.Loglevel(level)
-	}
+	application.SetLogLevel(level)
 }
 
 // Log an error.
+func (a *App) LogError(s ...any) {
+	if a.Logger != nil {
+		a.Logger.Error(s...)
+	}
+}
+
+// Log an error with the default application's logger.
 func LogError(s ...any) {
 	checkApp()
-	if application.Logger != nil {
-		application.Logger.Error(s...)
-	}
+	application.LogError(s...)
 }
 
 // Log an info message.
+func (a *App) LogInfo(s ...any) {
+	if a.Logger != nil {
+		a.Logger.Info(s...)
+	}
+}
+
+// Log an info message with the default application's logger.
 func LogInfo(s ...any) {
 	checkApp()
-	if application.Logger != nil {
-		application.Logger.Info(s...)
-	}
+	application.LogInfo(s...)
 }
 
 // Log a debug message.
+func (a *App) LogDebug(s ...any) {
+	if a.Logger != nil {
+		a.Logger.Debug(s...)
+	}
+}
+
+// Log a debug message with the default application's logger.
 func LogDebug(s ...any) {
 	checkApp()
-	if application.Logger != nil {
-		application.Logger.Debug(s...)
-	}
+	application.LogDebug(s...)
 }
 
 // Log an error in Sprintf format.
 //
 // This function will spin up a goroutine to log the message.
+func (a *App) LogErrorf(format string, v ...interface{}) {
+	a.LogError(fmt.Sprintf(format, v...))
+}
+
+// Log an error in Sprintf format with the default application's logger.
 func LogErrorf(format string, v ...interface{}) {
 	checkApp()
-	LogError(fmt.Sprintf(format, v...))
+	application.LogErrorf(format, v...)
 }
 
 // Log an info message in Sprintf format.
 //
 // This function will spin up a goroutine to log the message.
+func (a *App) LogInfof(format string, v ...interface{}) {
+	a.LogInfo(fmt.Sprintf(format, v...))
+}
+
+// Log an info message in Sprintf format with the default application's logger.
 func LogInfof(format string, v ...interface{}) {
 	checkApp()
-	LogInfo(fmt.Sprintf(format, v...))
+	application.LogInfof(format, v...)
 }
 
 // Log a debug message in Sprintf format.
 //
 // This function will spin up a goroutine to log the message.
+func (a *App) LogDebugf(format string, v ...interface{}) {
+	a.LogDebug(fmt.Sprintf(format, v...))
+}
+
+// Log a debug message in Sprintf format with the default application's logger.
 func LogDebugf(format string, v ...interface{}) {
 	checkApp()
-	LogDebug(fmt.Sprintf(format, v...))
+	application.LogDebugf(format, v...)
 }
 
 // An error message to be shown to the user.
 //
 // This function will spin up a goroutine to log the message.
-func ErrorMessage(d time.Duration, s ...any) {
-	checkApp()
-	if application.Messenger != nil {
-		if application.config.Flags.Has(F_LOG_EACH_MESSAGE) {
-			LogInfof("Logging error message: %s", s)
+func (a *App) ErrorMessage(d time.Duration, s ...any) {
+	if a.Messenger != nil {
+		if a.config.Flags.Has(F_LOG_EACH_MESSAGE) {
+			a.LogInfof("Logging error message: %s", s)
 		}
-		go application.Messenger.Error(d, s...)
+		go a.Messenger.Error(d, s...)
 	} else {
-		LogErrorf("Application does not have a messenger. Message: %s", s)
+		a.LogErrorf("Application does not have a messenger. Message: %s", s)
 	}
 }
 
+// An error message to be shown to the user by the default application's messenger.
+func ErrorMessage(d time.Duration, s ...any) {
+	checkApp()
+	application.ErrorMessage(d, s...)
+}
+
 // A warning message to be shown to the user.
 //
 // This function will spin up a goroutine to log the message.
-func WarningMessage(d time.Duration, s ...any) {
-	checkApp()
-	if application.Messenger != nil {
-		if application.config.Flags.Has(F_LOG_EACH_MESSAGE) {
-			LogInfof("Logging warning message: %s", s)
+func (a *App) WarningMessage(d time.Duration, s ...any) {
+	if a.Messenger != nil {
+		if a.config.Flags.Has(F_LOG_EACH_MESSAGE) {
+			a.LogInfof("Logging warning message: %s", s)
 		}
-		go application.Messenger.Warning(d, s...)
+		go a.Messenger.Warning(d, s...)
 	} else {
-		LogErrorf("Application does not have a messenger. Message: %s", s)
+		a.LogErrorf("Application does not have a messenger. Message: %s", s)
 	}
 }
 
+// A warning message to be shown to the user by the default application's messenger.
+func WarningMessage(d time.Duration, s ...any) {
+	checkApp()
+	application.WarningMessage(d, s...)
+}
+
 // An info message to be shown to the user.
 //
 // This function will spin up a goroutine to log the message.
-func InfoMessage(d time.Duration, s ...any) {
-	checkApp()
-	if application.Messenger != nil {
-		if application.config.Flags.Has(F_LOG_EACH_MESSAGE) {
-			LogInfof("Logging info message: %s", s)
+func (a *App) InfoMessage(d time.Duration, s ...any) {
+	if a.Messenger != nil {
+		if a.config.Flags.Has(F_LOG_EACH_MESSAGE) {
+			a.LogInfof("Logging info message: %s", s)
 		}
-		go application.Messenger.Info(d, s...)
+		go a.Messenger.Info(d, s...)
 	} else {
-		LogErrorf("Application does not have a messenger. Message: %s", s)
+		a.LogErrorf("Application does not have a messenger. Message: %s", s)
 	}
 }
 
+// An info message to be shown to the user by the default application's messenger.
+func InfoMessage(d time.Duration, s ...any) {
+	checkApp()
+	application.InfoMessage(d, s...)
+}
+
 // A success message to be shown to the user.
 //
 // This function will spin up a goroutine to log the message.
-func SuccessMessage(d time.Duration, s ...any) {
-	checkApp()
-	if application.Messenger != nil {
-		if application.config.Flags.Has(F_LOG_EACH_MESSAGE) {
-			LogInfof("Logging success message: %s", s)
+func (a *App) SuccessMessage(d time.Duration, s ...any) {
+	if a.Messenger != nil {
+		if a.config.Flags.Has(F_LOG_EACH_MESSAGE) {
+			a.LogInfof("Logging success message: %s", s)
 		}
-		go application.Messenger.Success(d, s...)
+		go a.Messenger.Success(d, s...)
 	} else {
-		LogErrorf("Application does not have a messenger. Message: %s", s)
+		a.LogErrorf("Application does not have a messenger. Message: %s", s)
 	}
 }
 
+// A success message to be shown to the user by the default application's messenger.
+func SuccessMessage(d time.Duration, s ...any) {
+	checkApp()
+	application.SuccessMessage(d, s...)
+}
+
 // WithLoader sets the application's loader.
+func (a *App) WithLoader(l Loader) {
+	a.Loader = l
+}
+
+// WithLoader sets the default application's loader.
 func WithLoader(l Loader) {
 	checkApp()
-	application.Loader = l
+	application.WithLoader(l)
 }
 
 // WithLogger sets the application's logger.
+func (a *App) WithLogger(l Logger) {
+	a.Logger = l
+}
+
+// WithLogger sets the default application's logger.
 func WithLogger(l Logger) {
 	checkApp()
-	application.Logger = l
+	application.WithLogger(l)
 }
 
 // WithMessenger sets the application's messenger.
+func (a *App) WithMessenger(m Messenger) {
+	a.Messenger = m
+}
+
+// WithMessenger sets the default application's messenger.
 func WithMessenger(m Messenger) {
 	checkApp()
-	application.Messenger = m
+	application.WithMessenger(m)
 }
 
 var dataGlobal = js.Global()
@@ -620,15 +1048,23 @@ func GlobalJS() js.Value {
 
 // Set global data for the application, and javascript global scope
 // If specified, but this means it must supported by jsext.ValueOf()
-func SetGlobal(key string, value interface{}, setGLobal bool) {
-	checkApp()
-	application.Data[key] = value
+//
+// The javascript global scope is shared by all applications.
+func (a *App) SetGlobal(key string, value interface{}, setGLobal bool) {
+	a.Data[key] = value
 	if setGLobal {
 		dataGlobal.Set(key, jsext.ValueOf(value).MarshalJS())
+		a.globalKeys[key] = struct{}{}
 	}
 }
 
-// Get global data for the application.
+// Set global data for the default application, see App.SetGlobal.
+func SetGlobal(key string, value interface{}, setGLobal bool) {
+	checkApp()
+	application.SetGlobal(key, value, setGLobal)
+}
+
+// Get global data for the default application.
 //
 // This function will look in the javascript global scope for the data if it is not found in the application's data.
 //
@@ -637,7 +1073,12 @@ func SetGlobal(key string, value interface{}, setGLobal bool) {
 // If T implements jsext.Unmarshaller, it will be used to unmarshal the javascript value.
 func GetGlobal[T any](key string) (ret T, ok bool) {
 	checkApp()
-	if v, ok := application.Data[key]; ok {
+	return GetAppGlobal[T](application, key)
+}
+
+// Get global data for the application, see GetGlobal.
+func GetAppGlobal[T any](a *App, key string) (ret T, ok bool) {
+	if v, ok := a.Data[key]; ok {
 		if ret, ok = v.(T); ok {
 			return ret, ok
 		}
@@ -672,11 +1113,11 @@ func GetGlobal[T any](key string) (ret T, ok bool) {
 // This function will be available to all pages, and in the global javascript scope.
 //
 // Arguments (if any) are limited to the types supported by jsext.ToGo()
-func SetGlobalFunc(name string, f func(args ...interface{}) Marshaller) (js.Func, error) {
+func (a *App) SetGlobalFunc(name string, f func(args ...interface{}) Marshaller) (js.Func, error) {
 	if name == "" {
 		return js.Func{Value: js.Null()}, fmt.Errorf("name cannot be empty")
 	}
-	var _, ok = application.globalFuncs[name]
+	var _, ok = a.globalFuncs[name]
 	if ok {
 		return js.Func{Value: js.Null()}, fmt.Errorf("global function %s already exists in application globals", name)
 	}
@@ -684,7 +1125,7 @@ func SetGlobalFunc(name string, f func(args ...interface{}) Marshaller) (js.Func
 	if !globFunc.IsNull() && !globFunc.IsUndefined() {
 		return js.Func{Value: js.Null()}, fmt.Errorf("global function %s already exists in javascript globals", name)
 	}
-	application.globalFuncs[name] = f
+	a.globalFuncs[name] = f
 	var jsFunc = js.FuncOf(func(_ js.Value, args []js.Value) interface{} {
 		var (
 			iargs = make([]interface{}, len(args))
@@ -699,20 +1140,27 @@ func SetGlobalFunc(name string, f func(args ...interface{}) Marshaller) (js.Func
 		return m.MarshalJS()
 	})
 	dataGlobal.Set(name, jsFunc)
+	a.globalJSFuncs[name] = jsFunc
 	return jsFunc, nil
 }
 
+// Add a global function to the default application, see App.SetGlobalFunc.
+func SetGlobalFunc(name string, f func(args ...interface{}) Marshaller) (js.Func, error) {
+	checkApp()
+	return application.SetGlobalFunc(name, f)
+}
+
 // Call a global function.
 //
 // This function will panic if the function does not exist.
 //
 // Arguments (if any) are limited to the types supported by jsext.ValueOf() if it is bound to js.Global alone,
 // otherwise it is limited to the types supported by jsext.ToGo()
-func ExecGlobalFunc(name string, args ...interface{}) Marshaller {
+func (a *App) ExecGlobalFunc(name string, args ...interface{}) Marshaller {
 	if name == "" {
 		panic("name cannot be empty")
 	}
-	var f, ok = application.globalFuncs[name]
+	var f, ok = a.globalFuncs[name]
 	if !ok {
 		var globFunc = dataGlobal.Get(name)
 		if globFunc.IsNull() || globFunc.IsUndefined() {
@@ -726,16 +1174,22 @@ func ExecGlobalFunc(name string, args ...interface{}) Marshaller {
 	return f(args...)
 }
 
+// Call a global function of the default application, see App.ExecGlobalFunc.
+func ExecGlobalFunc(name string, args ...interface{}) Marshaller {
+	checkApp()
+	return application.ExecGlobalFunc(name, args...)
+}
+
 // Check if a global value or function exists.
-func GlobalExists(name string) bool {
+func (a *App) GlobalExists(name string) bool {
 	if name == "" {
 		return false
 	}
-	var _, ok = application.globalFuncs[name]
+	var _, ok = a.globalFuncs[name]
 	if ok {
 		return true
 	}
-	_, ok = application.Data[name]
+	_, ok = a.Data[name]
 	if ok {
 		return true
 	}
@@ -746,21 +1200,37 @@ func GlobalExists(name string) bool {
 	return false
 }
 
+// Check if a global value or function exists in the default application.
+func GlobalExists(name string) bool {
+	checkApp()
+	return application.GlobalExists(name)
+}
+
 // WithEmbed sets the application's embed function.
 //
 // This can be used to embed the page element, useful for navbars, footers etc.
+func (a *App) WithEmbed(f func(pageCtx context.Context, page *jse.Element) *jse.Element) {
+	a.elementEmbedFunc = f
+}
+
+// WithEmbed sets the default application's embed function.
 func WithEmbed(f func(pageCtx context.Context, page *jse.Element) *jse.Element) {
 	checkApp()
-	application.elementEmbedFunc = f
+	application.WithEmbed(f)
 }
 
 // SetTemplate sets the application's template.
+func (a *App) SetTemplate(name string, f func(args ...interface{}) Marshaller) {
+	if a.templates == nil {
+		a.templates = make(map[string]func(args ...interface{}) Marshaller)
+	}
+	a.templates[name] = f
+}
+
+// SetTemplate sets the default application's template.
 func SetTemplate(name string, f func(args ...interface{}) Marshaller) {
 	checkApp()
-	if application.templates == nil {
-		application.templates = make(map[string]func(args ...interface{}) Marshaller)
-	}
-	application.templates[name] = f
+	application.SetTemplate(name, f)
 }
 
 // WithTemplate adds a template to the application.
@@ -768,52 +1238,77 @@ func SetTemplate(name string, f func(args ...interface{}) Marshaller) {
 // This function will panic if the template does not exist.
 //
 // The arguments passed to this function will be passed to the template function.
-func WithTemplate(name string, args ...interface{}) Marshaller {
-	checkApp()
-	if application.templates == nil {
-		application.templates = make(map[string]func(args ...interface{}) Marshaller)
+func (a *App) WithTemplate(name string, args ...interface{}) Marshaller {
+	if a.templates == nil {
+		a.templates = make(map[string]func(args ...interface{}) Marshaller)
 	}
 	// Some templates may be used more than once sequentially, we will cache the last used template.
-	if application.lastUsedTemplate != nil && application.lastUsedTemplate.name == name {
-		return application.lastUsedTemplate.fun(args...)
+	if a.lastUsedTemplate != nil && a.lastUsedTemplate.name == name {
+		return a.lastUsedTemplate.fun(args...)
 	}
-	var v, ok = application.templates[name]
+	var v, ok = a.templates[name]
 	if !ok || v == nil {
 		panic(fmt.Sprintf("Template %s not found", name))
 	}
-	application.lastUsedTemplate = &lastTemplate{
+	a.lastUsedTemplate = &lastTemplate{
 		name: name,
 		fun:  v,
 	}
 	return v(args...)
 }
 
-// WithoutTemplate removes a template from the application.
-func WithoutTemplate(name string) {
+// WithTemplate adds a template of the default application, see App.WithTemplate.
+func WithTemplate(name string, args ...interface{}) Marshaller {
 	checkApp()
-	if application.templates == nil {
+	return application.WithTemplate(name, args...)
+}
+
+// WithoutTemplate removes a template from the application.
+func (a *App) WithoutTemplate(name string) {
+	if a.templates == nil {
 		return
 	}
-	if application.lastUsedTemplate != nil && application.lastUsedTemplate.name == name {
-		application.lastUsedTemplate = nil
+	if a.lastUsedTemplate != nil && a.lastUsedTemplate.name == name {
+		a.lastUsedTemplate = nil
 	}
-	delete(application.templates, name)
+	delete(a.templates, name)
+}
+
+// WithoutTemplate removes a template from the default application.
+func WithoutTemplate(name string) {
+	checkApp()
+	application.WithoutTemplate(name)
 }
 
 // WithNotFoundHandler sets the application's not found handler.
+func (a *App) WithNotFoundHandler(h PageFunc) {
+	a.Mux.NotFoundHandler = a.makeHandleFunc(h, nil)
+}
+
+// WithNotFoundHandler sets the default application's not found handler.
 func WithNotFoundHandler(h PageFunc) {
 	checkApp()
-	application.Mux.NotFoundHandler = makeHandleFunc(h)
+	application.WithNotFoundHandler(h)
 }
 
 // WithOnResponseError sets the application's OnResponseError function.
+func (a *App) WithOnResponseError(f func(error)) {
+	a.OnResponseError = f
+}
+
+// WithOnResponseError sets the default application's OnResponseError function.
 func WithOnResponseError(f func(error)) {
 	checkApp()
-	application.OnResponseError = f
+	application.WithOnResponseError(f)
 }
 
 // WithFlags sets the application's flags.
+func (a *App) WithFlags(flags CraterFlags) {
+	a.config.Flags = flags
+}
+
+// WithFlags sets the default application's flags.
 func WithFlags(flags CraterFlags) {
 	checkApp()
-	application.config.Flags = flags
+	application.WithFlags(flags)
 }
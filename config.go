@@ -19,14 +19,20 @@ const (
 	// Log each message sent with crater.InfoMessage(), crater.ErrorMessage() etc.
 	F_LOG_EACH_MESSAGE
 
-	// Close all websocket connections after switching pages
+	// Close all websocket and event source connections after switching pages
 	//
-	// If not set, websocket connections will be kept open
+	// If not set, these connections will be kept open
 	// and must be closed manually, or they will be reused for the handler it was set on.
 	F_CLOSE_SOCKS_EACH_PAGE
 
 	// Append the canvas to the application's element instead of replacing it
 	F_APPEND_CANVAS
+
+	// Do not use the browser's location and history, the application keeps track of its own path.
+	//
+	// The application starts at the initial page URL, or "/" if it is not set.
+	// Use this for applications which are embedded next to others on the same page.
+	F_NO_HISTORY
 )
 
 // Check if the flag is set
@@ -38,6 +44,11 @@ type Config struct {
 	// The function which will be called when a page is not found
 	NotFoundHandler PageFunc `jsc:"-"`
 
+	// The page which will be served when a page panics.
+	//
+	// Page.Err will be a *PanicError. If nil, a simple error message is shown.
+	ErrorPage PageFunc `jsc:"-"`
+
 	// The function which will be called when an error occurs in HandleEndpoint()
 	OnResponseError func(error) `jsc:"-"`
 
@@ -77,4 +88,9 @@ type Config struct {
 	//
 	// The arguments passed to the function are the arguments passed to the template.
 	Templates map[string]func(args ...interface{}) Marshaller `jsc:"-"`
+
+	// Middleware which will be applied to every page in the application.
+	//
+	// Global middleware is always applied before route-level middleware.
+	Middleware []Middleware `jsc:"-"`
 }
@@ -0,0 +1,46 @@
+package crater
+
+import (
+	"context"
+
+	"github.com/Nigel2392/mux"
+)
+
+type contextKey string
+
+const (
+	pathContextKey         contextKey = "crater.Path"
+	variablesContextKey    contextKey = "crater.Variables"
+	navigationIDContextKey contextKey = "crater.NavigationID"
+)
+
+// Create the context for a page.
+//
+// The context is cancelled when the page is replaced.
+func newPageContext(nav *Navigation) (context.Context, context.CancelFunc) {
+	var ctx = context.Background()
+	ctx = context.WithValue(ctx, pathContextKey, nav.To)
+	ctx = context.WithValue(ctx, variablesContextKey, nav.ToVariables)
+	ctx = context.WithValue(ctx, navigationIDContextKey, nav.ID)
+	return context.WithCancel(ctx)
+}
+
+// PathFromContext returns the path of the page the context belongs to.
+func PathFromContext(ctx context.Context) string {
+	var path, _ = ctx.Value(pathContextKey).(string)
+	return path
+}
+
+// VariablesFromContext returns the variables of the page the context belongs to.
+func VariablesFromContext(ctx context.Context) mux.Variables {
+	var v, _ = ctx.Value(variablesContextKey).(mux.Variables)
+	return v
+}
+
+// NavigationIDFromContext returns the ID of the navigation which created the page the context belongs to.
+//
+// The ID is unique for each navigation, this can be used to check if a result is stale.
+func NavigationIDFromContext(ctx context.Context) uint64 {
+	var id, _ = ctx.Value(navigationIDContextKey).(uint64)
+	return id
+}
@@ -0,0 +1,316 @@
+package craterhttp
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Nigel2392/jsext/v2/errs"
+)
+
+var ErrNoToken = errs.Error("no token available")
+
+// Token is an access token which is sent in the Authorization header.
+type Token struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	RefreshToken string `json:"refresh_token"`
+
+	// The time at which the token expires, if zero, the token does not expire.
+	Expiry time.Time `json:"-"`
+}
+
+// Valid reports whether the token is set and has not expired.
+func (t *Token) Valid() bool {
+	return t != nil && t.AccessToken != "" && (t.Expiry.IsZero() || time.Now().Before(t.Expiry))
+}
+
+// Returns the value of the Authorization header for the token.
+func (t *Token) header() string {
+	var typ = t.TokenType
+	if typ == "" || strings.EqualFold(typ, "bearer") {
+		typ = "Bearer"
+	}
+	return typ + " " + t.AccessToken
+}
+
+// TokenSource provides the access tokens for Auth.
+type TokenSource interface {
+	// Token returns the current token.
+	Token(ctx context.Context) (*Token, error)
+
+	// Refresh retrieves a new token.
+	//
+	// This is called when the current token has expired,
+	// or the server responds with 401 Unauthorized.
+	Refresh(ctx context.Context) (*Token, error)
+}
+
+type authRetriedKey struct{}
+
+// Auth injects access tokens into requests made by a client.
+//
+// When the server responds with 401 Unauthorized, the token is refreshed and the request is retried once.
+// Concurrent refreshes are coalesced into a single call to TokenSource.Refresh.
+type Auth struct {
+	// Where the tokens are retrieved from.
+	Source TokenSource
+
+	// Called when refreshing the token fails.
+	//
+	// This can be used to redirect the user to a login page.
+	OnRefreshError func(err error)
+
+	// How long refreshing the token may take.
+	//
+	// If zero, 30 seconds is used.
+	RefreshTimeout time.Duration
+
+	mu         sync.Mutex
+	token      *Token
+	refreshing chan struct{}
+	refreshErr error
+}
+
+// Create a new Auth, use Auth.Install to add it to a client.
+func NewAuth(source TokenSource) *Auth {
+	return &Auth{
+		Source: source,
+	}
+}
+
+// Install adds the auth's interceptors to the client.
+func (a *Auth) Install(c *Client) {
+	c.UseRequest(a.interceptRequest)
+	c.UseResponse(a.interceptResponse)
+}
+
+// Returns the current token, retrieving or refreshing it if needed.
+func (a *Auth) current(ctx context.Context) (*Token, error) {
+	a.mu.Lock()
+	var token = a.token
+	a.mu.Unlock()
+	if token.Valid() {
+		return token, nil
+	}
+
+	var err error
+	if token == nil {
+		token, err = a.Source.Token(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if token.Valid() {
+			a.mu.Lock()
+			a.token = token
+			a.mu.Unlock()
+			return token, nil
+		}
+	}
+
+	if err = a.refresh(ctx, token); err != nil {
+		return nil, err
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.token, nil
+}
+
+// Refresh the token, unless it has already been replaced since stale was used.
+//
+// If a refresh is already in progress, wait for it to finish instead.
+// The refresh is shared by all callers, so it is not cancelled with ctx,
+// a caller stops waiting for it when ctx is done.
+func (a *Auth) refresh(ctx context.Context, stale *Token) error {
+	a.mu.Lock()
+	if a.token != nil && a.token != stale {
+		a.mu.Unlock()
+		return nil
+	}
+	var ch = a.refreshing
+	if ch == nil {
+		ch = make(chan struct{})
+		a.refreshing = ch
+		go a.doRefresh(ch)
+	}
+	a.mu.Unlock()
+
+	select {
+	case <-ch:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.refreshErr
+}
+
+// Retrieve a new token from the source, and close ch when done.
+func (a *Auth) doRefresh(ch chan struct{}) {
+	var timeout = a.RefreshTimeout
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+	var ctx, cancel = context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var token, err = a.Source.Refresh(ctx)
+	if err == nil && !token.Valid() {
+		err = ErrNoToken
+	}
+
+	a.mu.Lock()
+	if err == nil {
+		a.token = token
+	}
+	a.refreshErr = err
+	a.refreshing = nil
+	a.mu.Unlock()
+	close(ch)
+
+	if err != nil && a.OnRefreshError != nil {
+		a.OnRefreshError(err)
+	}
+}
+
+func (a *Auth) interceptRequest(r *Request) error {
+	var token, err = a.current(r.Context())
+	if err != nil {
+		return err
+	}
+	r.SetHeader("Authorization", token.header())
+	return nil
+}
+
+func (a *Auth) interceptResponse(r *Request, resp *Response, err error) (*Response, error) {
+	if err != nil || resp.StatusCode != 401 || r.Context().Value(authRetriedKey{}) != nil {
+		return resp, err
+	}
+
+	// Find the token which was used for the request.
+	a.mu.Lock()
+	var stale = a.token
+	a.mu.Unlock()
+	if stale != nil && headerValue(r.Headers, "Authorization") != stale.header() {
+		stale = nil
+	}
+
+	if a.refresh(r.Context(), stale) != nil {
+		return resp, nil
+	}
+
+	// Only retry the request once.
+	r.SetContext(context.WithValue(r.Context(), authRetriedKey{}, true))
+	return resp, ErrRetryRequest
+}
+
+// OAuth2TokenSource refreshes tokens with the OAuth2 refresh token grant.
+type OAuth2TokenSource struct {
+	// The client used to request new tokens.
+	//
+	// This should not be the client the Auth is installed on, if nil, DefaultClient is used.
+	Client *Client
+
+	// The URL of the token endpoint.
+	TokenURL string
+
+	ClientID     string
+	ClientSecret string
+	Scopes       []string
+
+	// Called when a new token is retrieved, this can be used to persist the refresh token.
+	OnToken func(t *Token)
+
+	mu    sync.Mutex
+	token *Token
+}
+
+// Create a new OAuth2 token source, starting with the given token.
+//
+// The token must have a refresh token.
+func NewOAuth2TokenSource(tokenURL, clientID string, token *Token) *OAuth2TokenSource {
+	return &OAuth2TokenSource{
+		TokenURL: tokenURL,
+		ClientID: clientID,
+		token:    token,
+	}
+}
+
+func (s *OAuth2TokenSource) Token(ctx context.Context) (*Token, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.token == nil {
+		return nil, ErrNoToken
+	}
+	return s.token, nil
+}
+
+func (s *OAuth2TokenSource) Refresh(ctx context.Context) (*Token, error) {
+	s.mu.Lock()
+	var current = s.token
+	s.mu.Unlock()
+	if current == nil || current.RefreshToken == "" {
+		return nil, ErrNoToken
+	}
+
+	var form = url.Values{
+		"grant_type":    {"refresh_token"},
+		"refresh_token": {current.RefreshToken},
+	}
+	if s.ClientID != "" {
+		form.Set("client_id", s.ClientID)
+	}
+	if s.ClientSecret != "" {
+		form.Set("client_secret", s.ClientSecret)
+	}
+	if len(s.Scopes) > 0 {
+		form.Set("scope", strings.Join(s.Scopes, " "))
+	}
+
+	var r, err = NewRequest("POST", s.TokenURL, form.Encode())
+	if err != nil {
+		return nil, err
+	}
+	r.SetHeader("Content-Type", "application/x-www-form-urlencoded")
+	r.SetContext(ctx)
+
+	var client = s.Client
+	if client == nil {
+		client = DefaultClient
+	}
+	resp, err := client.Do(r)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 400 {
+		resp.Body.Close()
+		return nil, errs.Error("craterhttp: token refresh failed: " + resp.Status)
+	}
+
+	var body struct {
+		Token
+		ExpiresIn int `json:"expires_in"`
+	}
+	if err = resp.JSON(&body); err != nil {
+		return nil, err
+	}
+	var token = body.Token
+	if body.ExpiresIn > 0 {
+		token.Expiry = time.Now().Add(time.Duration(body.ExpiresIn) * time.Second)
+	}
+	// The server may not issue a new refresh token.
+	if token.RefreshToken == "" {
+		token.RefreshToken = current.RefreshToken
+	}
+
+	s.mu.Lock()
+	s.token = &token
+	s.mu.Unlock()
+
+	if s.OnToken != nil {
+		s.OnToken(&token)
+	}
+	return &token, nil
+}
@@ -0,0 +1,125 @@
+package craterhttp_test
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Nigel2392/crater/craterhttp"
+	"github.com/Nigel2392/crater/craterhttp/craterhttptest"
+)
+
+// A token source which hands out "old", and "new" once refreshed.
+type tokenSource struct {
+	delay     time.Duration
+	refreshes int32
+
+	mu         sync.Mutex
+	token      string
+	refreshErr error
+}
+
+func (s *tokenSource) Token(ctx context.Context) (*craterhttp.Token, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return &craterhttp.Token{AccessToken: s.token}, nil
+}
+
+func (s *tokenSource) Refresh(ctx context.Context) (*craterhttp.Token, error) {
+	atomic.AddInt32(&s.refreshes, 1)
+	select {
+	case <-time.After(s.delay):
+	case <-ctx.Done():
+		s.mu.Lock()
+		s.refreshErr = ctx.Err()
+		s.mu.Unlock()
+		return nil, ctx.Err()
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.token = "new"
+	return &craterhttp.Token{AccessToken: s.token}, nil
+}
+
+// A transport which only accepts the new token.
+func authTransport() *craterhttptest.Transport {
+	var transport = craterhttptest.NewTransport()
+	transport.HandleFunc(func(r *craterhttp.Request) bool {
+		var auth = r.Headers["Authorization"]
+		return len(auth) == 1 && auth[0] == "Bearer new"
+	}, craterhttptest.Respond(200, "ok"))
+	transport.Handle("", "/*", craterhttptest.Respond(401, "unauthorized"))
+	return transport
+}
+
+func TestAuthConcurrentRefresh(t *testing.T) {
+	var source = &tokenSource{token: "old", delay: 20 * time.Millisecond}
+	var transport = authTransport()
+	var c = &craterhttp.Client{Transport: transport}
+	craterhttp.NewAuth(source).Install(c)
+
+	var statuses = make([]int, 5)
+	concurrently(len(statuses), func(i int) {
+		var resp, err = c.Do(newRequest(t, "GET", "/me"))
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		statuses[i] = resp.StatusCode
+	})
+
+	if n := atomic.LoadInt32(&source.refreshes); n != 1 {
+		t.Errorf("expected the concurrent 401s to share a single refresh, got %d", n)
+	}
+	for i, status := range statuses {
+		if status != 200 {
+			t.Errorf("expected request %d to be retried with the new token, got %d", i, status)
+		}
+	}
+	for _, r := range transport.Requests() {
+		if r.Response != nil && r.Response.StatusCode == 200 && r.Header("Authorization") != "Bearer new" {
+			t.Errorf("expected the retried request to use the new token, got %q", r.Header("Authorization"))
+		}
+	}
+}
+
+func TestAuthRefreshOutlivesCaller(t *testing.T) {
+	var source = &tokenSource{token: "old", delay: 50 * time.Millisecond}
+	var c = &craterhttp.Client{Transport: authTransport()}
+	craterhttp.NewAuth(source).Install(c)
+
+	// The first caller starts the refresh, and gives up before it finishes.
+	var ctx, cancel = context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	var first = newRequest(t, "GET", "/me")
+	first.SetContext(ctx)
+
+	var status int
+	concurrently(2, func(i int) {
+		if i == 0 {
+			c.Do(first)
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+		var resp, err = c.Do(newRequest(t, "GET", "/me"))
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		status = resp.StatusCode
+	})
+
+	if status != 200 {
+		t.Errorf("expected the second caller to receive the refreshed token, got %d", status)
+	}
+	source.mu.Lock()
+	defer source.mu.Unlock()
+	if source.refreshErr != nil {
+		t.Errorf("expected the refresh not to be cancelled with the first caller, got %v", source.refreshErr)
+	}
+	if n := atomic.LoadInt32(&source.refreshes); n != 1 {
+		t.Errorf("expected a single refresh, got %d", n)
+	}
+}
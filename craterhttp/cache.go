@@ -0,0 +1,293 @@
+package craterhttp
+
+import (
+	"bytes"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The time format used in HTTP headers, such as Expires and Last-Modified.
+const httpTimeFormat = "Mon, 02 Jan 2006 15:04:05 GMT"
+
+// CacheEntry is a response stored in the cache.
+type CacheEntry struct {
+	Status       string
+	StatusCode   int
+	Headers      map[string][]string
+	Body         []byte
+	StoredAt     time.Time
+	Expires      time.Time
+	ETag         string
+	LastModified string
+}
+
+// Fresh reports whether the entry can be served without revalidating it with the server.
+func (e *CacheEntry) Fresh() bool {
+	return time.Now().Before(e.Expires)
+}
+
+// Response creates a new response from the entry, with its own headers and body reader.
+func (e *CacheEntry) Response(r *Request) *Response {
+	return &Response{
+		Status:     e.Status,
+		StatusCode: e.StatusCode,
+		Headers:    copyHeaders(e.Headers),
+		Body:       io.NopCloser(bytes.NewReader(e.Body)),
+		Request:    r,
+	}
+}
+
+// CacheStorage is where the cache stores its entries.
+type CacheStorage interface {
+	// Get an entry by key.
+	Get(key string) (*CacheEntry, bool)
+
+	// Set an entry by key, replacing any existing entry.
+	Set(key string, e *CacheEntry) error
+
+	// Delete an entry by key.
+	Delete(key string) error
+
+	// Keys returns the keys of all entries in the storage.
+	Keys() []string
+}
+
+// Cache is an opt-in response cache for the client.
+//
+// Only GET and HEAD requests are cached, requests with a download progress function
+// or marked with StreamBody are not.
+//
+// Cache-Control (no-store, no-cache, max-age), Expires, ETag and Last-Modified headers are honored.
+// Stale entries with a validator are revalidated with a conditional request,
+// a 304 Not Modified response will serve the cached entry.
+type Cache struct {
+	// Where the entries are stored.
+	Storage CacheStorage
+
+	// How long entries are fresh for if the server does not specify it.
+	//
+	// If zero, responses without freshness information
+	// are only stored if they can be revalidated.
+	TTL time.Duration
+
+	// Request headers which are part of the cache key.
+	Vary []string
+}
+
+// Create a new cache with the given storage and default time to live.
+//
+// If storage is nil, a MemoryStorage will be used.
+func NewCache(storage CacheStorage, ttl time.Duration) *Cache {
+	if storage == nil {
+		storage = NewMemoryStorage()
+	}
+	return &Cache{
+		Storage: storage,
+		TTL:     ttl,
+	}
+}
+
+// Key returns the cache key for the request.
+//
+// The key is formatted as "METHOD URL", followed by "|header=value" for each of the Vary headers.
+func (c *Cache) Key(r *Request) string {
+	var b strings.Builder
+	b.WriteString(r.Method)
+	b.WriteString(" ")
+	b.WriteString(r.URL)
+	for _, name := range c.Vary {
+		b.WriteString("|")
+		b.WriteString(strings.ToLower(name))
+		b.WriteString("=")
+		b.WriteString(strings.Join(headerValues(r.Headers, name), ","))
+	}
+	return b.String()
+}
+
+// Invalidate deletes all entries of which the key starts with the prefix.
+//
+// To invalidate all cached GET requests to a URL, use "GET " followed by the URL.
+func (c *Cache) Invalidate(prefix string) error {
+	for _, key := range c.Storage.Keys() {
+		if !strings.HasPrefix(key, prefix) {
+			continue
+		}
+		if err := c.Storage.Delete(key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Check if the request can be served from the cache.
+//
+// Cached bodies are read up front, which would break download progress and streaming.
+func (c *Cache) cacheable(r *Request) bool {
+	if r.Method != "GET" && r.Method != "HEAD" {
+		return false
+	}
+	return downloadProgress(r) == nil && !streamed(r)
+}
+
+// Serve the request from the cache, calling next when the request needs to be made.
+func (c *Cache) do(r *Request, next func(r *Request) (*Response, error)) (*Response, error) {
+	var key = c.Key(r)
+	var entry, ok = c.Storage.Get(key)
+	if ok && entry.Fresh() {
+		return entry.Response(r), nil
+	}
+
+	// Revalidate the stale entry, the validators are set on a copy
+	// of the request so the caller's headers are left untouched.
+	var conditional = r
+	if ok {
+		conditional = copyRequest(r)
+		if entry.ETag != "" {
+			conditional.SetHeader("If-None-Match", entry.ETag)
+		}
+		if entry.LastModified != "" {
+			conditional.SetHeader("If-Modified-Since", entry.LastModified)
+		}
+	}
+
+	var resp, err = next(conditional)
+	if err != nil {
+		return nil, err
+	}
+	resp.Request = r
+
+	// The stored entry may be in use by other requests, store an updated copy instead.
+	//
+	// The headers of the 304 response replace the stored ones.
+	if ok && resp.StatusCode == 304 {
+		resp.Body.Close()
+		var updated = *entry
+		updated.Headers = mergeHeaders(entry.Headers, resp.Headers)
+		updated.StoredAt = time.Now()
+		updated.Expires = c.expires(updated.Headers, updated.StoredAt)
+		updated.ETag = headerValue(updated.Headers, "ETag")
+		updated.LastModified = headerValue(updated.Headers, "Last-Modified")
+		if err = c.Storage.Set(key, &updated); err != nil {
+			return nil, err
+		}
+		return updated.Response(r), nil
+	}
+
+	if resp.StatusCode != 200 || !c.storable(resp) {
+		return resp, nil
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	var now = time.Now()
+	entry = &CacheEntry{
+		Status:       resp.Status,
+		StatusCode:   resp.StatusCode,
+		Headers:      copyHeaders(resp.Headers),
+		Body:         body,
+		StoredAt:     now,
+		Expires:      c.expires(resp.Headers, now),
+		ETag:         headerValue(resp.Headers, "ETag"),
+		LastModified: headerValue(resp.Headers, "Last-Modified"),
+	}
+	if !entry.Fresh() && entry.ETag == "" && entry.LastModified == "" {
+		return resp, nil
+	}
+	return resp, c.Storage.Set(key, entry)
+}
+
+// Copy the request, the copy has its own headers.
+func copyRequest(r *Request) *Request {
+	var c = *r
+	c.Headers = copyHeaders(r.Headers)
+	return &c
+}
+
+// Copy the headers, the values are copied as well.
+func copyHeaders(headers map[string][]string) map[string][]string {
+	var c = make(map[string][]string, len(headers))
+	for key, values := range headers {
+		c[key] = append([]string(nil), values...)
+	}
+	return c
+}
+
+// Returns a copy of the headers, with the headers of update replacing those of the same name.
+func mergeHeaders(headers, update map[string][]string) map[string][]string {
+	var merged = copyHeaders(headers)
+	for name, values := range update {
+		for key := range merged {
+			if strings.EqualFold(key, name) {
+				delete(merged, key)
+			}
+		}
+		merged[name] = append([]string(nil), values...)
+	}
+	return merged
+}
+
+// Check if the response may be stored.
+func (c *Cache) storable(resp *Response) bool {
+	var _, noStore = cacheControl(resp.Headers)["no-store"]
+	return !noStore
+}
+
+// Returns the time at which a response stored at the given time becomes stale.
+func (c *Cache) expires(headers map[string][]string, now time.Time) time.Time {
+	var directives = cacheControl(headers)
+	if _, ok := directives["no-cache"]; ok {
+		return now
+	}
+	if maxAge, ok := directives["max-age"]; ok {
+		if seconds, err := strconv.Atoi(maxAge); err == nil {
+			return now.Add(time.Duration(seconds) * time.Second)
+		}
+	}
+	if expires := headerValue(headers, "Expires"); expires != "" {
+		if t, err := time.Parse(httpTimeFormat, expires); err == nil {
+			return t
+		}
+		return now
+	}
+	return now.Add(c.TTL)
+}
+
+// Parse the Cache-Control header into a map of directives.
+func cacheControl(headers map[string][]string) map[string]string {
+	var directives = make(map[string]string)
+	for _, value := range headerValues(headers, "Cache-Control") {
+		for _, directive := range strings.Split(value, ",") {
+			var name, arg, _ = strings.Cut(strings.TrimSpace(directive), "=")
+			directives[strings.ToLower(name)] = strings.Trim(arg, "\"")
+		}
+	}
+	return directives
+}
+
+// Returns the values of a header, the name is case-insensitive.
+func headerValues(headers map[string][]string, name string) []string {
+	var values []string
+	for key, v := range headers {
+		if strings.EqualFold(key, name) {
+			values = append(values, v...)
+		}
+	}
+	return values
+}
+
+// Returns the first value of a header, the name is case-insensitive.
+func headerValue(headers map[string][]string, name string) string {
+	for key, v := range headers {
+		if strings.EqualFold(key, name) && len(v) > 0 {
+			return v[0]
+		}
+	}
+	return ""
+}
@@ -0,0 +1,270 @@
+package craterhttp
+
+import (
+	"encoding/base64"
+	"strings"
+	"sync"
+	"syscall/js"
+	"time"
+
+	"github.com/Nigel2392/jsext/v2/encoding"
+	"github.com/Nigel2392/jsext/v2/errs"
+	"github.com/Nigel2392/jsext/v2/localstorage"
+)
+
+var ErrIndexedDBUndefined = errs.Error("indexedDB is undefined")
+
+// MemoryStorage stores cache entries in memory.
+//
+// Entries are lost when the page is reloaded.
+type MemoryStorage struct {
+	mu      sync.RWMutex
+	entries map[string]*CacheEntry
+}
+
+func NewMemoryStorage() *MemoryStorage {
+	return &MemoryStorage{
+		entries: make(map[string]*CacheEntry),
+	}
+}
+
+func (s *MemoryStorage) Get(key string) (*CacheEntry, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var e, ok = s.entries[key]
+	return e, ok
+}
+
+func (s *MemoryStorage) Set(key string, e *CacheEntry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries[key] = e
+	return nil
+}
+
+func (s *MemoryStorage) Delete(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.entries, key)
+	return nil
+}
+
+func (s *MemoryStorage) Keys() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var keys = make([]string, 0, len(s.entries))
+	for key := range s.entries {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// The representation of a cache entry in persistent storage.
+//
+// Only uses types which are supported by all encoders.
+type storedEntry struct {
+	Status       string              `json:"status"`
+	StatusCode   int                 `json:"statusCode"`
+	Headers      map[string][]string `json:"headers"`
+	Body         string              `json:"body"`
+	StoredAt     int64               `json:"storedAt"`
+	Expires      int64               `json:"expires"`
+	ETag         string              `json:"etag"`
+	LastModified string              `json:"lastModified"`
+}
+
+func encodeEntry(e *CacheEntry) (string, error) {
+	return encoding.EncodeJSON[string](&storedEntry{
+		Status:       e.Status,
+		StatusCode:   e.StatusCode,
+		Headers:      e.Headers,
+		Body:         base64.StdEncoding.EncodeToString(e.Body),
+		StoredAt:     e.StoredAt.UnixMilli(),
+		Expires:      e.Expires.UnixMilli(),
+		ETag:         e.ETag,
+		LastModified: e.LastModified,
+	})
+}
+
+func decodeEntry(data string) (*CacheEntry, error) {
+	var stored storedEntry
+	if err := encoding.DecodeJSON(data, &stored); err != nil {
+		return nil, err
+	}
+	var body, err = base64.StdEncoding.DecodeString(stored.Body)
+	if err != nil {
+		return nil, err
+	}
+	return &CacheEntry{
+		Status:       stored.Status,
+		StatusCode:   stored.StatusCode,
+		Headers:      stored.Headers,
+		Body:         body,
+		StoredAt:     time.UnixMilli(stored.StoredAt),
+		Expires:      time.UnixMilli(stored.Expires),
+		ETag:         stored.ETag,
+		LastModified: stored.LastModified,
+	}, nil
+}
+
+// LocalStorage stores cache entries in the browser's localStorage.
+//
+// All keys are prefixed with the storage's prefix, to avoid collisions with other data.
+type LocalStorage struct {
+	Prefix string
+}
+
+func NewLocalStorage(prefix string) *LocalStorage {
+	return &LocalStorage{
+		Prefix: prefix,
+	}
+}
+
+func (s *LocalStorage) Get(key string) (*CacheEntry, bool) {
+	var data, err = localstorage.Get(s.Prefix + key)
+	if err != nil {
+		return nil, false
+	}
+	e, err := decodeEntry(data)
+	if err != nil {
+		return nil, false
+	}
+	return e, true
+}
+
+func (s *LocalStorage) Set(key string, e *CacheEntry) error {
+	var data, err = encodeEntry(e)
+	if err != nil {
+		return err
+	}
+	return localstorage.Set(s.Prefix+key, data)
+}
+
+func (s *LocalStorage) Delete(key string) error {
+	return localstorage.Remove(s.Prefix + key)
+}
+
+func (s *LocalStorage) Keys() []string {
+	var storage = js.Global().Get("localStorage")
+	if storage.IsUndefined() {
+		return nil
+	}
+	var keys = make([]string, 0)
+	for i := 0; i < storage.Get("length").Int(); i++ {
+		var key = storage.Call("key", i).String()
+		if strings.HasPrefix(key, s.Prefix) {
+			keys = append(keys, strings.TrimPrefix(key, s.Prefix))
+		}
+	}
+	return keys
+}
+
+// IndexedDBStorage stores cache entries in an IndexedDB object store.
+//
+// This is useful for larger responses, which may not fit in localStorage.
+type IndexedDBStorage struct {
+	db    js.Value
+	store string
+}
+
+// Open (or create) an IndexedDB database with the given object store.
+func NewIndexedDBStorage(database, store string) (*IndexedDBStorage, error) {
+	var indexedDB = js.Global().Get("indexedDB")
+	if indexedDB.IsUndefined() {
+		return nil, ErrIndexedDBUndefined
+	}
+	var request = indexedDB.Call("open", database, 1)
+	var upgrade = js.FuncOf(func(this js.Value, args []js.Value) any {
+		var db = request.Get("result")
+		if !db.Get("objectStoreNames").Call("contains", store).Bool() {
+			db.Call("createObjectStore", store)
+		}
+		return nil
+	})
+	defer upgrade.Release()
+	request.Set("onupgradeneeded", upgrade)
+
+	var db, err = awaitIDBRequest(request)
+	if err != nil {
+		return nil, err
+	}
+	return &IndexedDBStorage{
+		db:    db,
+		store: store,
+	}, nil
+}
+
+func (s *IndexedDBStorage) objectStore(mode string) js.Value {
+	return s.db.Call("transaction", s.store, mode).Call("objectStore", s.store)
+}
+
+func (s *IndexedDBStorage) Get(key string) (*CacheEntry, bool) {
+	var result, err = awaitIDBRequest(s.objectStore("readonly").Call("get", key))
+	if err != nil || result.Type() != js.TypeString {
+		return nil, false
+	}
+	e, err := decodeEntry(result.String())
+	if err != nil {
+		return nil, false
+	}
+	return e, true
+}
+
+func (s *IndexedDBStorage) Set(key string, e *CacheEntry) error {
+	var data, err = encodeEntry(e)
+	if err != nil {
+		return err
+	}
+	_, err = awaitIDBRequest(s.objectStore("readwrite").Call("put", data, key))
+	return err
+}
+
+func (s *IndexedDBStorage) Delete(key string) error {
+	var _, err = awaitIDBRequest(s.objectStore("readwrite").Call("delete", key))
+	return err
+}
+
+func (s *IndexedDBStorage) Keys() []string {
+	var result, err = awaitIDBRequest(s.objectStore("readonly").Call("getAllKeys"))
+	if err != nil {
+		return nil
+	}
+	var keys = make([]string, result.Length())
+	for i := range keys {
+		keys[i] = result.Index(i).String()
+	}
+	return keys
+}
+
+// Wait for an IDBRequest to finish, returning its result.
+func awaitIDBRequest(request js.Value) (js.Value, error) {
+	var (
+		resultCh         = make(chan js.Value, 1)
+		errCh            = make(chan error, 1)
+		success, failure js.Func
+	)
+	success = js.FuncOf(func(this js.Value, args []js.Value) any {
+		resultCh <- request.Get("result")
+		return nil
+	})
+	defer success.Release()
+	failure = js.FuncOf(func(this js.Value, args []js.Value) any {
+		var err = request.Get("error")
+		if err.IsNull() || err.IsUndefined() {
+			errCh <- errs.Error("craterhttp: IndexedDB request failed")
+		} else {
+			errCh <- errs.Error("craterhttp: IndexedDB request failed: " + err.Get("message").String())
+		}
+		return nil
+	})
+	defer failure.Release()
+	request.Set("onsuccess", success)
+	request.Set("onerror", failure)
+
+	select {
+	case result := <-resultCh:
+		return result, nil
+	case err := <-errCh:
+		return js.Undefined(), err
+	}
+}
@@ -0,0 +1,128 @@
+package craterhttp_test
+
+import (
+	"testing"
+
+	"github.com/Nigel2392/crater/craterhttp"
+	"github.com/Nigel2392/crater/craterhttp/craterhttptest"
+)
+
+func respondWithHeaders(status int, headers map[string][]string, body string) craterhttptest.Responder {
+	return func(r *craterhttp.Request) (*craterhttp.Response, error) {
+		var h = make(map[string][]string, len(headers))
+		for k, v := range headers {
+			h[k] = append([]string(nil), v...)
+		}
+		return craterhttptest.NewResponse(status, h, []byte(body)), nil
+	}
+}
+
+func cachingClient() (*craterhttp.Client, *craterhttptest.Transport) {
+	var transport = craterhttptest.NewTransport()
+	return &craterhttp.Client{Transport: transport, Cache: craterhttp.NewCache(nil, 0)}, transport
+}
+
+func TestCacheServesFreshEntries(t *testing.T) {
+	var c, transport = cachingClient()
+	transport.Handle("GET", "/fresh", respondWithHeaders(200, map[string][]string{
+		"Cache-Control": {"max-age=60"},
+	}, "fresh"))
+
+	if body := readBody(t, get(t, c, "/fresh")); body != "fresh" {
+		t.Fatalf("expected the body, got %q", body)
+	}
+
+	// Changing a cached response must not change the cache.
+	var resp = get(t, c, "/fresh")
+	resp.Headers["Cache-Control"] = []string{"changed"}
+	if body := readBody(t, resp); body != "fresh" {
+		t.Errorf("expected the cached body, got %q", body)
+	}
+
+	resp = get(t, c, "/fresh")
+	if got := resp.Headers["Cache-Control"]; len(got) != 1 || got[0] != "max-age=60" {
+		t.Errorf("expected the cached headers to be unchanged, got %v", got)
+	}
+	transport.AssertCount(t, "GET", "/fresh", 1)
+}
+
+func TestCacheRevalidatesStaleEntries(t *testing.T) {
+	var c, transport = cachingClient()
+	transport.Handle("GET", "/stale", respondWithHeaders(200, map[string][]string{
+		"Cache-Control": {"no-cache"},
+		"Etag":          {`"v1"`},
+		"Last-Modified": {"Mon, 02 Jan 2006 15:04:05 GMT"},
+	}, "body")).Once()
+	transport.Handle("GET", "/stale", respondWithHeaders(304, map[string][]string{
+		"Cache-Control": {"max-age=60"},
+		"Etag":          {`"v2"`},
+		"Last-Modified": {"Tue, 03 Jan 2006 15:04:05 GMT"},
+	}, "")).Once()
+
+	get(t, c, "/stale")
+
+	var r = newRequest(t, "GET", "/stale")
+	var resp, err = c.Do(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != 200 || readBody(t, resp) != "body" {
+		t.Errorf("expected the 304 to serve the cached entry, got %d", resp.StatusCode)
+	}
+	if len(r.Headers["If-None-Match"]) > 0 || len(r.Headers["If-Modified-Since"]) > 0 {
+		t.Errorf("expected the caller's headers to be left untouched, got %v", r.Headers)
+	}
+
+	var revalidation = transport.Requests()[1]
+	if got := revalidation.Header("If-None-Match"); got != `"v1"` {
+		t.Errorf("expected the ETag to be sent, got %q", got)
+	}
+	if got := revalidation.Header("If-Modified-Since"); got != "Mon, 02 Jan 2006 15:04:05 GMT" {
+		t.Errorf("expected Last-Modified to be sent, got %q", got)
+	}
+
+	// The headers of the 304 made the entry fresh, and replaced the validators.
+	var entry, ok = c.Cache.Storage.Get(c.Cache.Key(r))
+	if !ok || !entry.Fresh() {
+		t.Fatalf("expected the entry to be fresh after the 304")
+	}
+	if entry.ETag != `"v2"` || entry.LastModified != "Tue, 03 Jan 2006 15:04:05 GMT" {
+		t.Errorf("expected the validators of the 304, got %q and %q", entry.ETag, entry.LastModified)
+	}
+	resp = get(t, c, "/stale")
+	if got := resp.Headers["Cache-Control"]; len(got) != 1 || got[0] != "max-age=60" {
+		t.Errorf("expected the headers of the 304, got %v", resp.Headers)
+	}
+	transport.AssertCount(t, "GET", "/stale", 2)
+}
+
+func TestCacheNoStore(t *testing.T) {
+	var c, transport = cachingClient()
+	transport.Handle("GET", "/private", respondWithHeaders(200, map[string][]string{
+		"Cache-Control": {"no-store, max-age=60"},
+	}, "private"))
+
+	get(t, c, "/private")
+	get(t, c, "/private")
+
+	transport.AssertCount(t, "GET", "/private", 2)
+	if keys := c.Cache.Storage.Keys(); len(keys) != 0 {
+		t.Errorf("expected nothing to be stored, got %v", keys)
+	}
+}
+
+func TestCacheSkipsStreams(t *testing.T) {
+	var c, transport = cachingClient()
+	transport.Handle("GET", "/stream", respondWithHeaders(200, map[string][]string{
+		"Cache-Control": {"max-age=60"},
+	}, "chunk"))
+
+	for i := 0; i < 2; i++ {
+		var r = newRequest(t, "GET", "/stream")
+		craterhttp.StreamBody(r)
+		if _, err := c.Do(r); err != nil {
+			t.Fatal(err)
+		}
+	}
+	transport.AssertCount(t, "GET", "/stream", 2)
+}
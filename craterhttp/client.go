@@ -11,6 +11,31 @@ type Client struct {
 	DefaultHeaders func(r *Request) map[string][]string
 	OnResponse     func(*Response) error
 	Timeout        time.Duration
+
+	// The transport used to send requests, if nil, FetchTransport is used.
+	Transport Transport
+
+	// An optional response cache, GET and HEAD requests will be served from it when possible.
+	Cache *Cache
+
+	// An optional retry policy, if nil, requests are only attempted once.
+	Retry *RetryPolicy
+
+	// Return an *HTTPError for responses with a status code outside of the 2xx range.
+	//
+	// If not set, these responses are returned without an error.
+	ErrorOnStatus bool
+
+	// Coalesce identical concurrent GET and HEAD requests into a single round trip.
+	//
+	// All callers share the response, each receives its own copy of the body.
+	// Requests with a download progress function, or marked with StreamBody, are not coalesced.
+	Coalesce bool
+
+	flight flightGroup
+
+	requestInterceptors  []RequestInterceptor
+	responseInterceptors []ResponseInterceptor
 }
 
 func NewClient(timeout time.Duration) *Client {
@@ -52,7 +77,7 @@ func (c *Client) Do(r *Request) (*Response, error) {
 		}
 	}
 
-	var resp, err = Fetch(r)
+	var resp, err = c.intercept(r)
 	if err != nil {
 		return nil, err
 	}
@@ -63,5 +88,37 @@ func (c *Client) Do(r *Request) (*Response, error) {
 		}
 	}
 
+	if c.ErrorOnStatus && (resp.StatusCode < 200 || resp.StatusCode >= 300) {
+		return nil, NewHTTPError(resp)
+	}
+
 	return resp, nil
 }
+
+// Send the request, coalescing it with identical requests in flight if enabled.
+func (c *Client) roundTrip(r *Request) (*Response, error) {
+	if c.Coalesce && c.flight.coalescable(r) {
+		return c.flight.do(r, c.cached)
+	}
+	return c.cached(r)
+}
+
+// Send the request, serving it from the cache if possible.
+func (c *Client) cached(r *Request) (*Response, error) {
+	if c.Cache != nil && c.Cache.cacheable(r) {
+		return c.Cache.do(r, c.send)
+	}
+	return c.send(r)
+}
+
+// Send the request over the network, retrying it if a retry policy is set.
+func (c *Client) send(r *Request) (*Response, error) {
+	var transport = c.Transport
+	if transport == nil {
+		transport = FetchTransport
+	}
+	if c.Retry != nil {
+		return c.Retry.do(r, transport.RoundTrip)
+	}
+	return transport.RoundTrip(r)
+}
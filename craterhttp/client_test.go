@@ -0,0 +1,53 @@
+package craterhttp_test
+
+import (
+	"io"
+	"sync"
+	"testing"
+
+	// The fake DOM must be installed before jsext is initialized.
+	_ "github.com/Nigel2392/crater/cratertest/fakedom"
+
+	"github.com/Nigel2392/crater/craterhttp"
+)
+
+func newRequest(t *testing.T, method, url string) *craterhttp.Request {
+	t.Helper()
+	var r, err = craterhttp.NewRequest(method, url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+func get(t *testing.T, c *craterhttp.Client, url string) *craterhttp.Response {
+	t.Helper()
+	var resp, err = c.Do(newRequest(t, "GET", url))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp
+}
+
+func readBody(t *testing.T, resp *craterhttp.Response) string {
+	t.Helper()
+	var body, err = io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	return string(body)
+}
+
+// Run f n times concurrently, and wait for all calls to return.
+func concurrently(n int, f func(i int)) {
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			f(i)
+		}(i)
+	}
+	wg.Wait()
+}
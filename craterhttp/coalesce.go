@@ -0,0 +1,109 @@
+package craterhttp
+
+import (
+	"context"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// A request which is in flight, shared by all callers making the same request.
+type flightCall struct {
+	done  chan struct{}
+	entry *CacheEntry
+	err   error
+}
+
+// Coalesces identical concurrent requests into a single round trip.
+type flightGroup struct {
+	mu    sync.Mutex
+	calls map[string]*flightCall
+}
+
+// Returns the key which identifies identical requests.
+func flightKey(r *Request) string {
+	var b strings.Builder
+	b.WriteString(r.Method)
+	b.WriteString(" ")
+	b.WriteString(r.URL)
+	var names = make([]string, 0, len(r.Headers))
+	for name := range r.Headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		b.WriteString("|")
+		b.WriteString(strings.ToLower(name))
+		b.WriteString("=")
+		b.WriteString(strings.Join(r.Headers[name], ","))
+	}
+	return b.String()
+}
+
+// Check if the request can share a round trip with identical requests.
+//
+// The body of a coalesced response is read up front, which would break
+// download progress and streaming, so these requests are made on their own.
+func (g *flightGroup) coalescable(r *Request) bool {
+	if r.Method != "GET" && r.Method != "HEAD" {
+		return false
+	}
+	return downloadProgress(r) == nil && !streamed(r)
+}
+
+// Make the request, or wait for an identical request which is already in flight.
+//
+// Each caller receives its own response, with its own copy of the body.
+func (g *flightGroup) do(r *Request, next func(r *Request) (*Response, error)) (*Response, error) {
+	var key = flightKey(r)
+
+	g.mu.Lock()
+	if g.calls == nil {
+		g.calls = make(map[string]*flightCall)
+	}
+	if call, ok := g.calls[key]; ok {
+		g.mu.Unlock()
+		select {
+		case <-call.done:
+		case <-r.Context().Done():
+			return nil, r.Context().Err()
+		}
+		// The request was aborted by the caller which made it, make it ourselves.
+		if (call.err == context.Canceled || call.err == context.DeadlineExceeded) && r.Context().Err() == nil {
+			return next(r)
+		}
+		if call.err != nil {
+			return nil, call.err
+		}
+		return call.entry.Response(r), nil
+	}
+	var call = &flightCall{done: make(chan struct{})}
+	g.calls[key] = call
+	g.mu.Unlock()
+
+	var resp, err = next(r)
+	if err == nil {
+		var body []byte
+		body, err = io.ReadAll(resp.Body)
+		resp.Body.Close()
+		call.entry = &CacheEntry{
+			Status:     resp.Status,
+			StatusCode: resp.StatusCode,
+			Headers:    resp.Headers,
+			Body:       body,
+		}
+	}
+	call.err = err
+
+	g.mu.Lock()
+	delete(g.calls, key)
+	g.mu.Unlock()
+	close(call.done)
+
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = call.entry.Response(r).Body
+	return resp, nil
+}
@@ -0,0 +1,57 @@
+package craterhttp_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Nigel2392/crater/craterhttp"
+	"github.com/Nigel2392/crater/craterhttp/craterhttptest"
+)
+
+func coalescingClient() (*craterhttp.Client, *craterhttptest.Transport) {
+	var transport = craterhttptest.NewTransport()
+	transport.Handle("GET", "/items", craterhttptest.Respond(200, "items")).Delay(20 * time.Millisecond)
+	return &craterhttp.Client{Transport: transport, Coalesce: true}, transport
+}
+
+func TestCoalesceConcurrentRequests(t *testing.T) {
+	var c, transport = coalescingClient()
+
+	var bodies = make([]string, 4)
+	concurrently(len(bodies), func(i int) {
+		bodies[i] = readBody(t, get(t, c, "/items"))
+	})
+
+	transport.AssertCount(t, "GET", "/items", 1)
+	for i, body := range bodies {
+		if body != "items" {
+			t.Errorf("expected caller %d to receive its own copy of the body, got %q", i, body)
+		}
+	}
+
+	// Requests which are not in flight at the same time are not coalesced.
+	get(t, c, "/items")
+	transport.AssertCount(t, "GET", "/items", 2)
+}
+
+func TestCoalesceSkipsStreamsAndProgress(t *testing.T) {
+	var marks = map[string]func(r *craterhttp.Request){
+		"stream": craterhttp.StreamBody,
+		"progress": func(r *craterhttp.Request) {
+			craterhttp.OnDownloadProgress(r, func(loaded, total int64) {})
+		},
+	}
+	for name, mark := range marks {
+		t.Run(name, func(t *testing.T) {
+			var c, transport = coalescingClient()
+			concurrently(3, func(i int) {
+				var r = newRequest(t, "GET", "/items")
+				mark(r)
+				if _, err := c.Do(r); err != nil {
+					t.Error(err)
+				}
+			})
+			transport.AssertCount(t, "GET", "/items", 3)
+		})
+	}
+}
@@ -0,0 +1,77 @@
+package craterhttptest
+
+import (
+	"strings"
+	"testing"
+)
+
+// AssertRequested fails the test if no request with the method and a URL matching the pattern was sent.
+func (t *Transport) AssertRequested(tb testing.TB, method, pattern string) *RecordedRequest {
+	tb.Helper()
+	var found = t.Find(method, pattern)
+	if len(found) == 0 {
+		tb.Errorf("expected a request for %s %s, got: %s", method, pattern, t.describeRequests())
+		return nil
+	}
+	return found[len(found)-1]
+}
+
+// AssertNotRequested fails the test if a request with the method and a URL matching the pattern was sent.
+func (t *Transport) AssertNotRequested(tb testing.TB, method, pattern string) {
+	tb.Helper()
+	if found := t.Find(method, pattern); len(found) > 0 {
+		tb.Errorf("expected no request for %s %s, got %d", method, pattern, len(found))
+	}
+}
+
+// AssertCount fails the test if the number of requests with the method
+// and a URL matching the pattern is not n.
+func (t *Transport) AssertCount(tb testing.TB, method, pattern string, n int) {
+	tb.Helper()
+	if found := t.Find(method, pattern); len(found) != n {
+		tb.Errorf("expected %d requests for %s %s, got %d", n, method, pattern, len(found))
+	}
+}
+
+// AssertAllCalled fails the test if a route did not handle any requests.
+func (t *Transport) AssertAllCalled(tb testing.TB) {
+	tb.Helper()
+	t.mu.Lock()
+	var unused = make([]string, 0)
+	for _, route := range t.routes {
+		if route.calls == 0 {
+			unused = append(unused, route.name)
+		}
+	}
+	t.mu.Unlock()
+	if len(unused) > 0 {
+		tb.Errorf("routes were not called: %s", strings.Join(unused, ", "))
+	}
+}
+
+// AssertNoUnmatched fails the test if a request matched no route.
+func (t *Transport) AssertNoUnmatched(tb testing.TB) {
+	tb.Helper()
+	for _, r := range t.Requests() {
+		if r.Route == nil {
+			tb.Errorf("request matched no route: %s %s", r.Method, r.URL)
+		}
+	}
+}
+
+func (t *Transport) describeRequests() string {
+	var requests = t.Requests()
+	if len(requests) == 0 {
+		return "no requests"
+	}
+	var b strings.Builder
+	for i, r := range requests {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteString(r.Method)
+		b.WriteString(" ")
+		b.WriteString(r.URL)
+	}
+	return b.String()
+}
@@ -0,0 +1,78 @@
+package craterhttptest
+
+import (
+	"bytes"
+	"io"
+	"sync"
+
+	"github.com/Nigel2392/crater/craterhttp"
+	"github.com/Nigel2392/jsext/v2/encoding"
+	"github.com/Nigel2392/jsext/v2/fetch"
+)
+
+// Responder returns the response for a request handled by a route.
+type Responder func(r *craterhttp.Request) (*craterhttp.Response, error)
+
+// NewResponse creates a response with the status code, headers and body.
+func NewResponse(status int, headers map[string][]string, body []byte) *craterhttp.Response {
+	if headers == nil {
+		headers = make(map[string][]string)
+	}
+	return &craterhttp.Response{
+		StatusCode: status,
+		Status:     fetch.StatusText(status),
+		Headers:    headers,
+		Body:       io.NopCloser(bytes.NewReader(body)),
+	}
+}
+
+// Respond with the status code and body.
+func Respond(status int, body string) Responder {
+	return RespondBytes(status, "text/plain; charset=utf-8", []byte(body))
+}
+
+// Respond with the status code, content type and body.
+func RespondBytes(status int, contentType string, body []byte) Responder {
+	return func(r *craterhttp.Request) (*craterhttp.Response, error) {
+		return NewResponse(status, map[string][]string{
+			"Content-Type": {contentType},
+		}, body), nil
+	}
+}
+
+// Respond with the status code, and v encoded as JSON.
+//
+// v is encoded when the responder is created, so later changes to it are not reflected.
+func RespondJSON(status int, v any) Responder {
+	var body, err = encoding.EncodeJSON[[]byte](v)
+	if err != nil {
+		return RespondError(err)
+	}
+	return RespondBytes(status, "application/json", body)
+}
+
+// Fail the request with err, like a network error would.
+func RespondError(err error) Responder {
+	return func(r *craterhttp.Request) (*craterhttp.Response, error) {
+		return nil, err
+	}
+}
+
+// Respond with each responder in turn, the last responder is repeated.
+//
+// This can be used to fail the first attempts of a request, to test retries.
+func Sequence(responders ...Responder) Responder {
+	var (
+		mu sync.Mutex
+		i  int
+	)
+	return func(r *craterhttp.Request) (*craterhttp.Response, error) {
+		mu.Lock()
+		var responder = responders[i]
+		if i < len(responders)-1 {
+			i++
+		}
+		mu.Unlock()
+		return responder(r)
+	}
+}
@@ -0,0 +1,319 @@
+// Package craterhttptest provides a mock transport for craterhttp clients,
+// so pages and tasks which make requests can be tested without a server.
+//
+// Install the transport on the client used by the application:
+//
+//	var transport = craterhttptest.NewTransport()
+//	transport.Handle("GET", "/api/users/*", craterhttptest.RespondJSON(200, user))
+//	crater.Client().Transport = transport
+package craterhttptest
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/url"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Nigel2392/crater/craterhttp"
+	"github.com/Nigel2392/crater/decoder"
+	"github.com/Nigel2392/jsext/v2/errs"
+)
+
+var ErrNoRoute = errs.Error("craterhttptest: no route matches the request")
+
+// RecordedRequest is a request which was sent through a Transport.
+type RecordedRequest struct {
+	Method  string
+	URL     string
+	Headers map[string][]string
+	Body    []byte
+
+	// The route which handled the request, nil if it was passed to the fallback transport.
+	Route *Route
+
+	// The response and error returned for the request.
+	Response *craterhttp.Response
+	Err      error
+}
+
+// JSON decodes the body of the request into dst.
+func (r *RecordedRequest) JSON(dst any) error {
+	return decoder.JSONDecoder.DecodeResponse(io.NopCloser(bytes.NewReader(r.Body)), dst)
+}
+
+// Header returns the first value of the request header.
+func (r *RecordedRequest) Header(key string) string {
+	for k, v := range r.Headers {
+		if strings.EqualFold(k, key) && len(v) > 0 {
+			return v[0]
+		}
+	}
+	return ""
+}
+
+// Transport is a craterhttp.Transport which serves requests from registered routes,
+// and records every request sent through it.
+//
+// Routes are matched in the order they were added.
+type Transport struct {
+	// The transport for requests which match no route.
+	//
+	// If nil, these requests fail with ErrNoRoute.
+	// Set this to craterhttp.FetchTransport to record requests to a real server.
+	Fallback craterhttp.Transport
+
+	// Latency added to every request handled by a route.
+	Latency time.Duration
+
+	mu       sync.Mutex
+	routes   []*Route
+	requests []*RecordedRequest
+}
+
+// Create a new mock transport without any routes.
+func NewTransport() *Transport {
+	return &Transport{}
+}
+
+// Create a transport which records all requests, and sends them with next.
+func NewRecorder(next craterhttp.Transport) *Transport {
+	return &Transport{
+		Fallback: next,
+	}
+}
+
+// Handle adds a route for requests with the method and a URL matching the pattern.
+//
+// An empty method matches any method.
+// The pattern is matched with path.Match against the path of the URL,
+// or against the URL without its query if the pattern contains "://".
+func (t *Transport) Handle(method, pattern string, responder Responder) *Route {
+	return t.HandleFunc(func(r *craterhttp.Request) bool {
+		return (method == "" || strings.EqualFold(method, r.Method)) && matchURL(pattern, r.URL)
+	}, responder).describe(method, pattern)
+}
+
+// HandleFunc adds a route for requests for which match returns true.
+func (t *Transport) HandleFunc(match func(r *craterhttp.Request) bool, responder Responder) *Route {
+	var route = &Route{
+		t:         t,
+		match:     match,
+		responder: responder,
+		name:      "custom route",
+	}
+	t.mu.Lock()
+	t.routes = append(t.routes, route)
+	t.mu.Unlock()
+	return route
+}
+
+func (t *Transport) RoundTrip(r *craterhttp.Request) (*craterhttp.Response, error) {
+	var rec = &RecordedRequest{
+		Method:  r.Method,
+		URL:     r.URL,
+		Headers: copyHeaders(r.Headers),
+		Body:    requestBody(r),
+	}
+
+	t.mu.Lock()
+	var route *Route
+	for _, rt := range t.routes {
+		if rt.take(r) {
+			route = rt
+			break
+		}
+	}
+	t.requests = append(t.requests, rec)
+	var latency = t.Latency
+	t.mu.Unlock()
+
+	if route == nil {
+		if t.Fallback == nil {
+			rec.Err = fmt.Errorf("%w: %s %s", ErrNoRoute, r.Method, r.URL)
+		} else {
+			rec.Response, rec.Err = t.Fallback.RoundTrip(r)
+		}
+		return rec.Response, rec.Err
+	}
+	rec.Route = route
+
+	if err := wait(r, latency+route.delay); err != nil {
+		rec.Err = err
+		return nil, err
+	}
+
+	rec.Response, rec.Err = route.responder(r)
+	if rec.Response != nil {
+		if rec.Response.Request == nil {
+			rec.Response.Request = r
+		}
+		for key, values := range route.headers {
+			if rec.Response.Headers == nil {
+				rec.Response.Headers = make(map[string][]string)
+			}
+			rec.Response.Headers[key] = append(rec.Response.Headers[key], values...)
+		}
+	}
+	return rec.Response, rec.Err
+}
+
+// Requests returns the requests sent through the transport, in order.
+func (t *Transport) Requests() []*RecordedRequest {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var requests = make([]*RecordedRequest, len(t.requests))
+	copy(requests, t.requests)
+	return requests
+}
+
+// Find returns the requests with the method and a URL matching the pattern.
+//
+// The method and pattern are matched like in Transport.Handle.
+func (t *Transport) Find(method, pattern string) []*RecordedRequest {
+	var found = make([]*RecordedRequest, 0)
+	for _, r := range t.Requests() {
+		if (method == "" || strings.EqualFold(method, r.Method)) && matchURL(pattern, r.URL) {
+			found = append(found, r)
+		}
+	}
+	return found
+}
+
+// Reset removes all routes and recorded requests.
+func (t *Transport) Reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.routes = nil
+	t.requests = nil
+}
+
+// Route is a route of a Transport.
+type Route struct {
+	t         *Transport
+	match     func(r *craterhttp.Request) bool
+	responder Responder
+	name      string
+	delay     time.Duration
+	headers   map[string][]string
+	remaining int
+	limited   bool
+	calls     int
+}
+
+func (r *Route) describe(method, pattern string) *Route {
+	if method == "" {
+		method = "*"
+	}
+	r.name = method + " " + pattern
+	return r
+}
+
+// Delay adds latency to the responses of the route.
+func (r *Route) Delay(d time.Duration) *Route {
+	r.delay = d
+	return r
+}
+
+// Header adds a header to the responses of the route.
+func (r *Route) Header(key, value string) *Route {
+	if r.headers == nil {
+		r.headers = make(map[string][]string)
+	}
+	r.headers[key] = append(r.headers[key], value)
+	return r
+}
+
+// Times limits the number of requests the route handles,
+// after which requests fall through to the next matching route.
+func (r *Route) Times(n int) *Route {
+	r.remaining = n
+	r.limited = true
+	return r
+}
+
+// Once limits the route to handling a single request.
+func (r *Route) Once() *Route {
+	return r.Times(1)
+}
+
+// Calls returns the number of requests the route handled.
+func (r *Route) Calls() int {
+	r.t.mu.Lock()
+	defer r.t.mu.Unlock()
+	return r.calls
+}
+
+func (r *Route) String() string {
+	return r.name
+}
+
+// Reports whether the route handles the request, counting the call if it does.
+func (r *Route) take(req *craterhttp.Request) bool {
+	if r.limited && r.remaining <= 0 {
+		return false
+	}
+	if !r.match(req) {
+		return false
+	}
+	if r.limited {
+		r.remaining--
+	}
+	r.calls++
+	return true
+}
+
+// Wait for the latency, or until the request's context is done.
+func wait(r *craterhttp.Request, d time.Duration) error {
+	var ctx = r.Context()
+	if d <= 0 {
+		return ctx.Err()
+	}
+	var timer = time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func matchURL(pattern, rawURL string) bool {
+	var u, err = url.Parse(rawURL)
+	if err != nil {
+		return pattern == rawURL
+	}
+	var target = u.Path
+	if strings.Contains(pattern, "://") {
+		u.RawQuery = ""
+		u.Fragment = ""
+		target = u.String()
+	}
+	var ok, _ = path.Match(pattern, target)
+	return ok
+}
+
+func copyHeaders(headers map[string][]string) map[string][]string {
+	var c = make(map[string][]string, len(headers))
+	for key, values := range headers {
+		c[key] = append([]string(nil), values...)
+	}
+	return c
+}
+
+func requestBody(r *craterhttp.Request) []byte {
+	if r.Body != nil || r.GetBody == nil {
+		return r.Body
+	}
+	var body, err = r.GetBody()
+	if err != nil {
+		return nil
+	}
+	defer body.Close()
+	data, _ := io.ReadAll(body)
+	return data
+}
@@ -0,0 +1,133 @@
+package craterhttptest_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	// The fake DOM must be installed before jsext is initialized.
+	_ "github.com/Nigel2392/crater/cratertest/fakedom"
+
+	"github.com/Nigel2392/crater/craterhttp"
+	"github.com/Nigel2392/crater/craterhttp/craterhttptest"
+)
+
+func do(t *testing.T, c *craterhttp.Client, method, url string, body any) (*craterhttp.Response, error) {
+	t.Helper()
+	var r, err = craterhttp.NewRequest(method, url, body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c.Do(r)
+}
+
+func readBody(t *testing.T, resp *craterhttp.Response) string {
+	t.Helper()
+	var body, err = io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(body)
+}
+
+func TestTransportRoutes(t *testing.T) {
+	var transport = craterhttptest.NewTransport()
+	var user = transport.Handle("GET", "/api/users/*", craterhttptest.RespondJSON(200, map[string]any{"name": "alice"})).
+		Header("X-Request-Id", "1")
+	var create = transport.Handle("POST", "/api/users", craterhttptest.Respond(201, "created"))
+	var c = &craterhttp.Client{Transport: transport}
+
+	resp, err := do(t, c, "GET", "https://example.com/api/users/1", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var v map[string]any
+	if err = resp.JSON(&v); err != nil {
+		t.Fatal(err)
+	}
+	if v["name"] != "alice" {
+		t.Errorf("expected the name to be alice, got %v", v["name"])
+	}
+	if got := resp.Headers["X-Request-Id"]; len(got) != 1 || got[0] != "1" {
+		t.Errorf("expected the route's header, got %v", resp.Headers)
+	}
+
+	resp, err = do(t, c, "POST", "/api/users", map[string]any{"name": "bob"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != 201 || readBody(t, resp) != "created" {
+		t.Errorf("expected 201 created, got %d", resp.StatusCode)
+	}
+
+	var rec = transport.AssertRequested(t, "POST", "/api/users")
+	if err = rec.JSON(&v); err != nil {
+		t.Fatal(err)
+	}
+	if v["name"] != "bob" || rec.Route != create {
+		t.Errorf("expected the recorded request to have the body and route, got %v %v", v, rec.Route)
+	}
+
+	if user.Calls() != 1 || create.Calls() != 1 {
+		t.Errorf("expected each route to be called once, got %d and %d", user.Calls(), create.Calls())
+	}
+	transport.AssertCount(t, "", "/api/users/*", 1)
+	transport.AssertNotRequested(t, "DELETE", "/api/users/*")
+	transport.AssertAllCalled(t)
+	transport.AssertNoUnmatched(t)
+}
+
+func TestTransportNoRoute(t *testing.T) {
+	var transport = craterhttptest.NewTransport()
+	var c = &craterhttp.Client{Transport: transport}
+
+	var _, err = do(t, c, "GET", "/missing", nil)
+	if !errors.Is(err, craterhttptest.ErrNoRoute) {
+		t.Fatalf("expected ErrNoRoute, got %v", err)
+	}
+	if requests := transport.Requests(); len(requests) != 1 || requests[0].Route != nil {
+		t.Errorf("expected the request to be recorded without a route, got %v", requests)
+	}
+}
+
+func TestTransportTimesAndSequence(t *testing.T) {
+	var transport = craterhttptest.NewTransport()
+	transport.Handle("GET", "/item", craterhttptest.Respond(200, "first")).Once()
+	transport.Handle("GET", "/item", craterhttptest.Sequence(
+		craterhttptest.Respond(500, "second"),
+		craterhttptest.Respond(200, "last"),
+	))
+	var c = &craterhttp.Client{Transport: transport}
+
+	for _, want := range []string{"first", "second", "last", "last"} {
+		var resp, err = do(t, c, "GET", "/item", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := readBody(t, resp); got != want {
+			t.Errorf("expected %q, got %q", want, got)
+		}
+	}
+}
+
+func TestTransportDelayRespectsContext(t *testing.T) {
+	var transport = craterhttptest.NewTransport()
+	transport.Handle("GET", "/slow", craterhttptest.Respond(200, "slow")).Delay(time.Second)
+	var c = &craterhttp.Client{Transport: transport}
+
+	var ctx, cancel = context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	var r, _ = craterhttp.NewRequest("GET", "/slow", nil)
+	r.SetContext(ctx)
+
+	var start = time.Now()
+	var _, err = c.Do(r)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the deadline to be exceeded, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("expected the request to stop with its context, took %s", elapsed)
+	}
+}
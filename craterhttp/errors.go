@@ -0,0 +1,113 @@
+package craterhttp
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+
+	"github.com/Nigel2392/crater/decoder"
+	"github.com/Nigel2392/jsext/v2/errs"
+)
+
+// ErrStatusNotOK is wrapped by HTTPError, use errors.Is to check for it.
+var ErrStatusNotOK = errs.Error("response status not ok")
+
+// The value of tasks.ErrRequestNotOK, which HTTPError also matches.
+//
+// The tasks package imports this package, so the error cannot be referenced directly.
+const errRequestNotOK = errs.Error("request not ok")
+
+// HTTPError is returned when the server responds with a status code outside of the 2xx range.
+//
+// The body is read when the error is created, and decoded lazily with Decode or JSON.
+type HTTPError struct {
+	Method     string
+	URL        string
+	StatusCode int
+	Status     string
+	Headers    map[string][]string
+
+	body    []byte
+	readErr error
+}
+
+// Create a new HTTPError from the response, this reads and closes the response body.
+func NewHTTPError(resp *Response) *HTTPError {
+	var e = &HTTPError{
+		StatusCode: resp.StatusCode,
+		Status:     resp.Status,
+		Headers:    resp.Headers,
+	}
+	if resp.Request != nil {
+		e.Method = resp.Request.Method
+		e.URL = resp.Request.URL
+	}
+	if resp.Body != nil {
+		e.body, e.readErr = io.ReadAll(resp.Body)
+		resp.Body.Close()
+	}
+	return e
+}
+
+func (e *HTTPError) Error() string {
+	return "craterhttp: " + e.Method + " " + e.URL + ": " + e.Status
+}
+
+func (e *HTTPError) Unwrap() error {
+	return ErrStatusNotOK
+}
+
+func (e *HTTPError) Is(target error) bool {
+	return target == errRequestNotOK
+}
+
+// Bytes returns the raw response body.
+func (e *HTTPError) Bytes() ([]byte, error) {
+	return e.body, e.readErr
+}
+
+// Decode the response body into dst with the given decoder.
+func (e *HTTPError) Decode(d Decoder, dst any) error {
+	if e.readErr != nil {
+		return e.readErr
+	}
+	return d.DecodeResponse(io.NopCloser(bytes.NewReader(e.body)), dst)
+}
+
+// JSON decodes the response body into dst.
+func (e *HTTPError) JSON(dst any) error {
+	return e.Decode(decoder.JSONDecoder, dst)
+}
+
+// Message returns a message which can be shown to the user.
+//
+// This is the "message", "error" or "detail" field of a JSON body,
+// the body itself if it is short plain text, or the status otherwise.
+func (e *HTTPError) Message() string {
+	var body map[string]any
+	if e.JSON(&body) == nil {
+		for _, key := range []string{"message", "error", "detail"} {
+			if msg, ok := body[key].(string); ok && msg != "" {
+				return msg
+			}
+		}
+	}
+	var text = strings.TrimSpace(string(e.body))
+	if text != "" && len(text) <= 200 && !strings.HasPrefix(text, "<") && !strings.HasPrefix(text, "{") {
+		return text
+	}
+	return e.Status
+}
+
+// ErrorBody decodes the JSON body of an HTTPError into E.
+//
+// It returns false if err is not an HTTPError, or the body could not be decoded.
+func ErrorBody[E any](err error) (E, bool) {
+	var body E
+	var httpErr *HTTPError
+	if !errors.As(err, &httpErr) {
+		return body, false
+	}
+	return body, httpErr.JSON(&body) == nil
+}
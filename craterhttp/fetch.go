@@ -3,9 +3,16 @@ package craterhttp
 import "github.com/Nigel2392/jsext/v2/fetch"
 
 func Fetch(r *Request) (*Response, error) {
+	var download = downloadProgress(r)
+	if upload := uploadProgress(r); upload != nil {
+		return fetchXHR(r, upload, download)
+	}
 	var resp, err = fetch.Fetch((*fetch.Request)(r))
 	if err != nil {
 		return nil, err
 	}
+	if download != nil {
+		resp.Body = newProgressReader(resp.Body, resp.Headers, download)
+	}
 	return (*Response)(resp), nil
 }
@@ -0,0 +1,173 @@
+package craterhttp
+
+import (
+	"bytes"
+	"io"
+	"mime/multipart"
+	"net/textproto"
+	"net/url"
+	"strings"
+	"syscall/js"
+
+	"github.com/Nigel2392/jsext/v2/errs"
+)
+
+// NewFormRequest creates a request with an application/x-www-form-urlencoded body.
+func NewFormRequest(method string, url string, values url.Values) (*Request, error) {
+	var r, err = NewRequest(method, url, values.Encode())
+	if err != nil {
+		return nil, err
+	}
+	r.SetHeader("Content-Type", "application/x-www-form-urlencoded")
+	return r, nil
+}
+
+var quoteEscaper = strings.NewReplacer("\\", "\\\\", `"`, "\\\"")
+
+// MultipartForm builds a multipart/form-data request body.
+//
+// Files can be added from readers, or from javascript Blob and File objects,
+// such as the files of an <input type="file"> element.
+type MultipartForm struct {
+	buf    bytes.Buffer
+	writer *multipart.Writer
+}
+
+// Create a new, empty multipart form.
+func NewMultipartForm() *MultipartForm {
+	var f = &MultipartForm{}
+	f.writer = multipart.NewWriter(&f.buf)
+	return f
+}
+
+// Create a new multipart form from a <form> element.
+//
+// All fields of the form are added, including the selected files.
+func NewMultipartFormFromElement(form js.Value) (*MultipartForm, error) {
+	var f = NewMultipartForm()
+	var entries = js.Global().Get("FormData").New(form).Call("entries")
+	for {
+		var next = entries.Call("next")
+		if next.Get("done").Bool() {
+			break
+		}
+		var (
+			pair  = next.Get("value")
+			name  = pair.Index(0).String()
+			value = pair.Index(1)
+		)
+		var err error
+		if value.Type() == js.TypeString {
+			err = f.AddField(name, value.String())
+		} else {
+			err = f.AddBlob(name, value)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return f, nil
+}
+
+// AddField adds a text field to the form.
+func (f *MultipartForm) AddField(name, value string) error {
+	return f.writer.WriteField(name, value)
+}
+
+// AddFile adds a file to the form, reading its contents from r.
+//
+// If the content type is empty, application/octet-stream is used.
+func (f *MultipartForm) AddFile(field, filename, contentType string, r io.Reader) error {
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	var header = make(textproto.MIMEHeader)
+	header.Set("Content-Disposition", `form-data; name="`+quoteEscaper.Replace(field)+`"; filename="`+quoteEscaper.Replace(filename)+`"`)
+	header.Set("Content-Type", contentType)
+	var part, err = f.writer.CreatePart(header)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(part, r)
+	return err
+}
+
+// AddBlob adds a javascript Blob or File to the form.
+//
+// The name and type of a File are used, a Blob is named "blob".
+func (f *MultipartForm) AddBlob(field string, blob js.Value) error {
+	var filename = "blob"
+	if name := blob.Get("name"); name.Type() == js.TypeString && name.String() != "" {
+		filename = name.String()
+	}
+	var contentType string
+	if typ := blob.Get("type"); typ.Type() == js.TypeString {
+		contentType = typ.String()
+	}
+	var buffer, err = awaitPromise(blob.Call("arrayBuffer"))
+	if err != nil {
+		return err
+	}
+	var array = js.Global().Get("Uint8Array").New(buffer)
+	var data = make([]byte, array.Get("byteLength").Int())
+	js.CopyBytesToGo(data, array)
+	return f.AddFile(field, filename, contentType, bytes.NewReader(data))
+}
+
+// AddFileInput adds all selected files of an <input type="file"> element to the form.
+func (f *MultipartForm) AddFileInput(field string, input js.Value) error {
+	var files = input.Get("files")
+	for i := 0; i < files.Length(); i++ {
+		if err := f.AddBlob(field, files.Index(i)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ContentType returns the Content-Type of the form, including the boundary.
+func (f *MultipartForm) ContentType() string {
+	return f.writer.FormDataContentType()
+}
+
+// Request finishes the form, and creates a request with the form as its body.
+//
+// No more fields can be added after this is called.
+func (f *MultipartForm) Request(method string, url string) (*Request, error) {
+	if err := f.writer.Close(); err != nil {
+		return nil, err
+	}
+	var r, err = NewRequest(method, url, f.buf.Bytes())
+	if err != nil {
+		return nil, err
+	}
+	r.SetHeader("Content-Type", f.ContentType())
+	return r, nil
+}
+
+// Wait for a javascript promise to settle, returning its result.
+func awaitPromise(promise js.Value) (js.Value, error) {
+	var (
+		resultCh         = make(chan js.Value, 1)
+		errCh            = make(chan error, 1)
+		success, failure js.Func
+	)
+	success = js.FuncOf(func(this js.Value, args []js.Value) any {
+		resultCh <- args[0]
+		return nil
+	})
+	defer success.Release()
+	failure = js.FuncOf(func(this js.Value, args []js.Value) any {
+		errCh <- errs.Error("craterhttp: promise rejected: " + args[0].Get("message").String())
+		return nil
+	})
+	defer failure.Release()
+	promise.Call("then", success, failure)
+
+	select {
+	case result := <-resultCh:
+		return result, nil
+	case err := <-errCh:
+		return js.Undefined(), err
+	}
+}
@@ -0,0 +1,67 @@
+package craterhttp
+
+import "github.com/Nigel2392/jsext/v2/errs"
+
+// ErrRetryRequest can be returned by a response interceptor to send the request again.
+//
+// Request interceptors will run again before the request is resent.
+var ErrRetryRequest = errs.Error("retry request")
+
+// The maximum number of times a request is resent because of ErrRetryRequest.
+const maxInterceptorRetries = 3
+
+// RequestInterceptor is called before the request is sent.
+//
+// It may modify the request, returning an error aborts the request.
+type RequestInterceptor func(r *Request) error
+
+// ResponseInterceptor is called after the request was sent.
+//
+// The error is set if the request failed, the response will then be nil.
+// It may inspect, replace or map the response and error, the returned values are passed to the next interceptor.
+//
+// Returning ErrRetryRequest sends the request again.
+type ResponseInterceptor func(r *Request, resp *Response, err error) (*Response, error)
+
+// UseRequest adds request interceptors to the client.
+//
+// Interceptors are called in the order they were added.
+func (c *Client) UseRequest(i ...RequestInterceptor) {
+	c.requestInterceptors = append(c.requestInterceptors, i...)
+}
+
+// UseResponse adds response interceptors to the client.
+//
+// Interceptors are called in the order they were added.
+func (c *Client) UseResponse(i ...ResponseInterceptor) {
+	c.responseInterceptors = append(c.responseInterceptors, i...)
+}
+
+// Run the request through the interceptors, and send it.
+func (c *Client) intercept(r *Request) (resp *Response, err error) {
+	for attempt := 0; ; attempt++ {
+		for _, interceptor := range c.requestInterceptors {
+			if err = interceptor(r); err != nil {
+				return nil, err
+			}
+		}
+
+		resp, err = c.roundTrip(r)
+		for _, interceptor := range c.responseInterceptors {
+			resp, err = interceptor(r, resp, err)
+			if err == ErrRetryRequest {
+				break
+			}
+		}
+
+		if err != ErrRetryRequest {
+			return resp, err
+		}
+		if attempt >= maxInterceptorRetries {
+			return nil, errs.Error("craterhttp: too many retries requested by interceptors")
+		}
+		if resp != nil && resp.Body != nil {
+			resp.Body.Close()
+		}
+	}
+}
@@ -0,0 +1,195 @@
+package craterhttp
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strconv"
+	"strings"
+	"syscall/js"
+
+	"github.com/Nigel2392/jsext/v2/errs"
+)
+
+// ProgressFunc is called as a body is transferred.
+//
+// Total is the total number of bytes, or -1 if it is unknown.
+type ProgressFunc func(loaded, total int64)
+
+type (
+	uploadProgressKey   struct{}
+	downloadProgressKey struct{}
+)
+
+// OnUploadProgress sets a function which is called as the request body is uploaded.
+//
+// The browser's fetch API does not report upload progress,
+// requests with an upload progress function are sent with XMLHttpRequest instead.
+// These responses are buffered, and cannot be streamed.
+func OnUploadProgress(r *Request, f ProgressFunc) {
+	r.SetContext(context.WithValue(r.Context(), uploadProgressKey{}, f))
+}
+
+// OnDownloadProgress sets a function which is called as the response body is read.
+func OnDownloadProgress(r *Request, f ProgressFunc) {
+	r.SetContext(context.WithValue(r.Context(), downloadProgressKey{}, f))
+}
+
+func uploadProgress(r *Request) ProgressFunc {
+	var f, _ = r.Context().Value(uploadProgressKey{}).(ProgressFunc)
+	return f
+}
+
+func downloadProgress(r *Request) ProgressFunc {
+	var f, _ = r.Context().Value(downloadProgressKey{}).(ProgressFunc)
+	return f
+}
+
+// Reports progress as the body is read.
+type progressReader struct {
+	body   io.ReadCloser
+	loaded int64
+	total  int64
+	fn     ProgressFunc
+}
+
+func newProgressReader(body io.ReadCloser, headers map[string][]string, fn ProgressFunc) *progressReader {
+	var total, err = strconv.ParseInt(headerValue(headers, "Content-Length"), 10, 64)
+	if err != nil {
+		total = -1
+	}
+	return &progressReader{
+		body:  body,
+		total: total,
+		fn:    fn,
+	}
+}
+
+func (r *progressReader) Read(p []byte) (int, error) {
+	var n, err = r.body.Read(p)
+	if n > 0 {
+		r.loaded += int64(n)
+		r.fn(r.loaded, r.total)
+	}
+	return n, err
+}
+
+func (r *progressReader) Close() error {
+	return r.body.Close()
+}
+
+// Send the request with XMLHttpRequest, reporting the upload and download progress.
+func fetchXHR(r *Request, upload, download ProgressFunc) (*Response, error) {
+	var body []byte
+	if r.Body != nil {
+		body = r.Body
+	} else if r.GetBody != nil {
+		var reader, err = r.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		body, err = io.ReadAll(reader)
+		reader.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var xhr = js.Global().Get("XMLHttpRequest").New()
+	xhr.Call("open", r.Method, r.URL, true)
+	xhr.Set("responseType", "arraybuffer")
+	for key, values := range r.Headers {
+		for _, v := range values {
+			xhr.Call("setRequestHeader", key, v)
+		}
+	}
+	if r.Credentials == "include" {
+		xhr.Set("withCredentials", true)
+	}
+
+	var (
+		doneCh = make(chan struct{}, 1)
+		errCh  = make(chan error, 1)
+		funcs  []js.Func
+	)
+	var on = func(target js.Value, event string, f func(e js.Value)) {
+		var fn = js.FuncOf(func(this js.Value, args []js.Value) any {
+			f(args[0])
+			return nil
+		})
+		funcs = append(funcs, fn)
+		target.Call("addEventListener", event, fn)
+	}
+	defer func() {
+		for _, fn := range funcs {
+			fn.Release()
+		}
+	}()
+
+	var progress = func(fn ProgressFunc) func(e js.Value) {
+		return func(e js.Value) {
+			var total int64 = -1
+			if e.Get("lengthComputable").Bool() {
+				total = int64(e.Get("total").Float())
+			}
+			fn(int64(e.Get("loaded").Float()), total)
+		}
+	}
+	if upload != nil {
+		on(xhr.Get("upload"), "progress", progress(upload))
+	}
+	if download != nil {
+		on(xhr, "progress", progress(download))
+	}
+	on(xhr, "load", func(e js.Value) { doneCh <- struct{}{} })
+	on(xhr, "error", func(e js.Value) { errCh <- errs.Error("craterhttp: XMLHttpRequest failed") })
+	on(xhr, "abort", func(e js.Value) { errCh <- errs.Error("craterhttp: XMLHttpRequest aborted") })
+
+	if body != nil && r.Method != "GET" && r.Method != "HEAD" {
+		var jsBody = js.Global().Get("Uint8Array").New(len(body))
+		js.CopyBytesToJS(jsBody, body)
+		xhr.Call("send", jsBody)
+	} else {
+		xhr.Call("send")
+	}
+
+	select {
+	case <-r.Context().Done():
+		xhr.Call("abort")
+		return nil, r.Context().Err()
+	case err := <-errCh:
+		return nil, err
+	case <-doneCh:
+	}
+
+	var data []byte
+	if result := xhr.Get("response"); !result.IsNull() && !result.IsUndefined() {
+		var array = js.Global().Get("Uint8Array").New(result)
+		data = make([]byte, array.Get("byteLength").Int())
+		js.CopyBytesToGo(data, array)
+	}
+
+	var code = xhr.Get("status").Int()
+	return &Response{
+		Status:     strconv.Itoa(code) + " " + xhr.Get("statusText").String(),
+		StatusCode: code,
+		Headers:    parseXHRHeaders(xhr.Call("getAllResponseHeaders").String()),
+		Body:       io.NopCloser(bytes.NewReader(data)),
+		Request:    r,
+		JS:         xhr,
+	}, nil
+}
+
+// Parse the headers returned by XMLHttpRequest.getAllResponseHeaders.
+func parseXHRHeaders(raw string) map[string][]string {
+	var headers = make(map[string][]string)
+	for _, line := range strings.Split(raw, "\r\n") {
+		var key, value, ok = strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		headers[key] = append(headers[key], strings.TrimSpace(value))
+	}
+	return headers
+}
@@ -0,0 +1,195 @@
+package craterhttp
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Links are the pagination links parsed from a Link header.
+type Links struct {
+	Next  string
+	Prev  string
+	First string
+	Last  string
+}
+
+// ParseLinkHeader parses a Link header into a map of relation to URL.
+//
+// For example: <https://api.example.com/items?cursor=abc>; rel="next"
+func ParseLinkHeader(value string) map[string]string {
+	var links = make(map[string]string)
+	for _, link := range strings.Split(value, ",") {
+		var parts = strings.Split(link, ";")
+		var target = strings.TrimSpace(parts[0])
+		if !strings.HasPrefix(target, "<") || !strings.HasSuffix(target, ">") {
+			continue
+		}
+		target = target[1 : len(target)-1]
+		for _, param := range parts[1:] {
+			var name, rel, _ = strings.Cut(strings.TrimSpace(param), "=")
+			if !strings.EqualFold(name, "rel") {
+				continue
+			}
+			for _, r := range strings.Fields(strings.Trim(rel, "\"")) {
+				links[strings.ToLower(r)] = target
+			}
+		}
+	}
+	return links
+}
+
+// Parse the pagination links from the response's Link header.
+func parseLinks(resp *Response) *Links {
+	var links = ParseLinkHeader(headerValue(resp.Headers, "Link"))
+	return &Links{
+		Next:  links["next"],
+		Prev:  links["prev"],
+		First: links["first"],
+		Last:  links["last"],
+	}
+}
+
+// PageQuery returns the query parameters for page based pagination.
+func PageQuery(page, size int) url.Values {
+	return url.Values{
+		"page":      {strconv.Itoa(page)},
+		"page_size": {strconv.Itoa(size)},
+	}
+}
+
+// OffsetQuery returns the query parameters for offset based pagination.
+func OffsetQuery(offset, limit int) url.Values {
+	return url.Values{
+		"offset": {strconv.Itoa(offset)},
+		"limit":  {strconv.Itoa(limit)},
+	}
+}
+
+// Resource is a typed JSON REST client for a collection of T.
+//
+// Items are addressed as BaseURL/{id}.
+//
+// Responses with a status code outside of the 2xx range are returned as an *HTTPError,
+// use ErrorBody to decode the error body into a typed value.
+type Resource[T any] struct {
+	// The client used to make the requests, if nil, DefaultClient is used.
+	Client *Client
+
+	// The URL of the collection.
+	BaseURL string
+}
+
+// Create a new resource for the collection at baseURL.
+func NewResource[T any](client *Client, baseURL string) *Resource[T] {
+	return &Resource[T]{
+		Client:  client,
+		BaseURL: strings.TrimSuffix(baseURL, "/"),
+	}
+}
+
+// Returns the URL of an item in the collection.
+func (r *Resource[T]) itemURL(id string) string {
+	return r.BaseURL + "/" + url.PathEscape(id)
+}
+
+// Make a request, decoding the response body into dst if it is not nil.
+//
+// If the response has no body, dst is left as is, so Create and Update return the zero value.
+func (r *Resource[T]) do(ctx context.Context, method, u string, body any, dst any) (*Response, error) {
+	var req, err = NewRequest(method, u, body)
+	if err != nil {
+		return nil, err
+	}
+	req.SetContext(ctx)
+	req.SetHeader("Accept", "application/json")
+
+	var client = r.Client
+	if client == nil {
+		client = DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp, NewHTTPError(resp)
+	}
+	defer resp.Body.Close()
+
+	if dst == nil || resp.StatusCode == 204 {
+		return resp, nil
+	}
+
+	// Responses to creates and updates often have no body, leave dst as is for these.
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return resp, err
+	}
+	if len(bytes.TrimSpace(data)) == 0 {
+		return resp, nil
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(data))
+	return resp, resp.JSON(dst)
+}
+
+// List the items in the collection, the query may be nil.
+//
+// The pagination links are parsed from the response's Link header.
+func (r *Resource[T]) List(ctx context.Context, query url.Values) ([]T, *Links, error) {
+	var u = r.BaseURL
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	return r.ListURL(ctx, u)
+}
+
+// List the items at the given URL.
+//
+// This can be used to follow cursor based pagination links returned by List.
+func (r *Resource[T]) ListURL(ctx context.Context, u string) ([]T, *Links, error) {
+	var items []T
+	var resp, err = r.do(ctx, "GET", u, nil, &items)
+	if err != nil {
+		return nil, nil, err
+	}
+	return items, parseLinks(resp), nil
+}
+
+// Get an item by its ID.
+func (r *Resource[T]) Get(ctx context.Context, id string) (T, error) {
+	var item T
+	var _, err = r.do(ctx, "GET", r.itemURL(id), nil, &item)
+	return item, err
+}
+
+// Create an item, returning the item as created by the server.
+func (r *Resource[T]) Create(ctx context.Context, item T) (T, error) {
+	var created T
+	var _, err = r.do(ctx, "POST", r.BaseURL, item, &created)
+	return created, err
+}
+
+// Update (replace) an item, returning the item as updated by the server.
+func (r *Resource[T]) Update(ctx context.Context, id string, item T) (T, error) {
+	var updated T
+	var _, err = r.do(ctx, "PUT", r.itemURL(id), item, &updated)
+	return updated, err
+}
+
+// Patch an item with a partial update, returning the item as updated by the server.
+func (r *Resource[T]) Patch(ctx context.Context, id string, patch any) (T, error) {
+	var updated T
+	var _, err = r.do(ctx, "PATCH", r.itemURL(id), patch, &updated)
+	return updated, err
+}
+
+// Delete an item by its ID.
+func (r *Resource[T]) Delete(ctx context.Context, id string) error {
+	var _, err = r.do(ctx, "DELETE", r.itemURL(id), nil, nil)
+	return err
+}
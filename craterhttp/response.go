@@ -47,3 +47,8 @@ func (r *Response) String() string {
 func (r *Response) JSON(dst any) error {
 	return decoder.JSONDecoder.DecodeResponse(r.Body, dst)
 }
+
+// DecodeResponse decodes the response body into dst with the given decoder.
+func (r *Response) DecodeResponse(d Decoder, dst any) error {
+	return d.DecodeResponse(r.Body, dst)
+}
@@ -0,0 +1,138 @@
+package craterhttp
+
+import (
+	"math/rand"
+	"strconv"
+	"time"
+)
+
+// RetryPolicy configures how the client retries failed requests.
+//
+// Requests are retried on network errors, 5xx responses and 429 Too Many Requests.
+// The Retry-After header is honored when the server sends it.
+//
+// Retries respect the request's context, and the client's Timeout.
+// If waiting for the next attempt would exceed the deadline, the last result is returned.
+type RetryPolicy struct {
+	// The maximum number of attempts, including the first one.
+	MaxAttempts int
+
+	// The delay before the first retry, this doubles after each attempt.
+	BaseDelay time.Duration
+
+	// The maximum delay between attempts, if zero, the delay is not capped.
+	MaxDelay time.Duration
+
+	// The fraction of the delay which is randomized, between 0 and 1.
+	Jitter float64
+
+	// Also retry requests which are not idempotent, such as POST and PATCH.
+	RetryNonIdempotent bool
+
+	// An optional function which decides if the request should be retried.
+	//
+	// This replaces the default check on network errors and status codes.
+	ShouldRetry func(resp *Response, err error) bool
+}
+
+// DefaultRetryPolicy returns a policy which makes up to 3 attempts,
+// starting with a 200 millisecond delay, capped at 5 seconds.
+func DefaultRetryPolicy() *RetryPolicy {
+	return &RetryPolicy{
+		MaxAttempts: 3,
+		BaseDelay:   200 * time.Millisecond,
+		MaxDelay:    5 * time.Second,
+		Jitter:      0.5,
+	}
+}
+
+// Check if the method can be safely retried.
+func idempotent(method string) bool {
+	switch method {
+	case "GET", "HEAD", "OPTIONS", "TRACE", "PUT", "DELETE":
+		return true
+	}
+	return false
+}
+
+// Check if the result of an attempt should be retried.
+func (p *RetryPolicy) shouldRetry(resp *Response, err error) bool {
+	if p.ShouldRetry != nil {
+		return p.ShouldRetry(resp, err)
+	}
+	if err != nil {
+		return true
+	}
+	return resp.StatusCode == 429 || resp.StatusCode >= 500
+}
+
+// Returns the delay before the given retry, starting at 1.
+func (p *RetryPolicy) backoff(retry int) time.Duration {
+	var delay = p.BaseDelay
+	for i := 1; i < retry; i++ {
+		delay *= 2
+		if p.MaxDelay > 0 && delay >= p.MaxDelay {
+			break
+		}
+	}
+	if p.MaxDelay > 0 && delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	if p.Jitter > 0 {
+		delay -= time.Duration(rand.Float64() * p.Jitter * float64(delay))
+	}
+	return delay
+}
+
+// Make the request, retrying it according to the policy.
+func (p *RetryPolicy) do(r *Request, next func(r *Request) (*Response, error)) (*Response, error) {
+	if !p.RetryNonIdempotent && !idempotent(r.Method) {
+		return next(r)
+	}
+
+	var ctx = r.Context()
+	for attempt := 1; ; attempt++ {
+		var resp, err = next(r)
+		if ctx.Err() != nil || attempt >= p.MaxAttempts || !p.shouldRetry(resp, err) {
+			return resp, err
+		}
+
+		var delay = p.backoff(attempt)
+		if resp != nil {
+			if retryAfter, ok := parseRetryAfter(headerValue(resp.Headers, "Retry-After")); ok && retryAfter > delay {
+				delay = retryAfter
+			}
+		}
+
+		// Do not wait if the deadline would pass before the next attempt.
+		if deadline, ok := ctx.Deadline(); ok && time.Now().Add(delay).After(deadline) {
+			return resp, err
+		}
+
+		if resp != nil && resp.Body != nil {
+			resp.Body.Close()
+		}
+
+		var timer = time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// Parse the Retry-After header, which is either a number of seconds or an HTTP date.
+func parseRetryAfter(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if t, err := time.Parse(httpTimeFormat, value); err == nil {
+		return time.Until(t), true
+	}
+	return 0, false
+}
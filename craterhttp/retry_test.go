@@ -0,0 +1,104 @@
+package craterhttp_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Nigel2392/crater/craterhttp"
+	"github.com/Nigel2392/crater/craterhttp/craterhttptest"
+)
+
+func retryingClient(policy *craterhttp.RetryPolicy) (*craterhttp.Client, *craterhttptest.Transport) {
+	var transport = craterhttptest.NewTransport()
+	return &craterhttp.Client{Transport: transport, Retry: policy}, transport
+}
+
+func TestRetryBackoff(t *testing.T) {
+	var c, transport = retryingClient(&craterhttp.RetryPolicy{
+		MaxAttempts: 3,
+		BaseDelay:   20 * time.Millisecond,
+	})
+	transport.Handle("GET", "/flaky", craterhttptest.Sequence(
+		craterhttptest.Respond(503, "unavailable"),
+		craterhttptest.Respond(503, "unavailable"),
+		craterhttptest.Respond(200, "ok"),
+	))
+
+	var start = time.Now()
+	var resp = get(t, c, "/flaky")
+	var elapsed = time.Since(start)
+
+	if resp.StatusCode != 200 {
+		t.Errorf("expected the last attempt to succeed, got %d", resp.StatusCode)
+	}
+	transport.AssertCount(t, "GET", "/flaky", 3)
+
+	// The delay doubles after each attempt: 20ms, then 40ms.
+	if elapsed < 60*time.Millisecond {
+		t.Errorf("expected the attempts to back off for at least 60ms, took %s", elapsed)
+	}
+}
+
+func TestRetryGivesUp(t *testing.T) {
+	var c, transport = retryingClient(&craterhttp.RetryPolicy{
+		MaxAttempts: 2,
+		BaseDelay:   time.Millisecond,
+	})
+	transport.Handle("GET", "/down", craterhttptest.Respond(500, "down"))
+	transport.Handle("POST", "/down", craterhttptest.Respond(500, "down"))
+
+	if resp := get(t, c, "/down"); resp.StatusCode != 500 {
+		t.Errorf("expected the last response to be returned, got %d", resp.StatusCode)
+	}
+	transport.AssertCount(t, "GET", "/down", 2)
+
+	// Requests which are not idempotent are not retried.
+	if _, err := c.Do(newRequest(t, "POST", "/down")); err != nil {
+		t.Fatal(err)
+	}
+	transport.AssertCount(t, "POST", "/down", 1)
+
+	// Client errors are not retried.
+	transport.Handle("GET", "/missing", craterhttptest.Respond(404, "missing"))
+	get(t, c, "/missing")
+	transport.AssertCount(t, "GET", "/missing", 1)
+}
+
+func TestRetryAfter(t *testing.T) {
+	var c, transport = retryingClient(&craterhttp.RetryPolicy{
+		MaxAttempts: 2,
+		BaseDelay:   time.Millisecond,
+	})
+	transport.Handle("GET", "/limited", craterhttptest.Sequence(
+		respondWithHeaders(429, map[string][]string{"Retry-After": {"1"}}, "slow down"),
+		craterhttptest.Respond(200, "ok"),
+	))
+
+	var start = time.Now()
+	var resp = get(t, c, "/limited")
+	if resp.StatusCode != 200 {
+		t.Errorf("expected the retry to succeed, got %d", resp.StatusCode)
+	}
+	if elapsed := time.Since(start); elapsed < time.Second {
+		t.Errorf("expected the retry to wait for Retry-After, took %s", elapsed)
+	}
+}
+
+func TestRetryAfterBeyondDeadline(t *testing.T) {
+	var c, transport = retryingClient(&craterhttp.RetryPolicy{
+		MaxAttempts: 2,
+		BaseDelay:   time.Millisecond,
+	})
+	c.Timeout = 100 * time.Millisecond
+	transport.Handle("GET", "/limited", respondWithHeaders(429, map[string][]string{"Retry-After": {"5"}}, "slow down"))
+
+	var start = time.Now()
+	var resp = get(t, c, "/limited")
+	if resp.StatusCode != 429 {
+		t.Errorf("expected the 429 to be returned, got %d", resp.StatusCode)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("expected not to wait past the deadline, took %s", elapsed)
+	}
+	transport.AssertCount(t, "GET", "/limited", 1)
+}
@@ -0,0 +1,111 @@
+package craterhttp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+
+	"github.com/Nigel2392/crater/decoder"
+)
+
+// The size of the buffer used to read chunks, chunks larger than this are split.
+const streamChunkSize = 32 * 1024
+
+type streamKey struct{}
+
+// StreamBody marks the request as one of which the response body is streamed,
+// with Response.Stream, Response.Lines or DecodeStream.
+//
+// These requests are never cached or coalesced, so the body is passed on as it arrives.
+func StreamBody(r *Request) {
+	r.SetContext(context.WithValue(r.Context(), streamKey{}, true))
+}
+
+func streamed(r *Request) bool {
+	var ok, _ = r.Context().Value(streamKey{}).(bool)
+	return ok
+}
+
+// BodyStream reads a response body chunk by chunk, as the chunks arrive from the server.
+type BodyStream struct {
+	body io.ReadCloser
+	buf  []byte
+}
+
+// Stream returns a stream which reads the body as it arrives.
+//
+// Mark the request with StreamBody, so the body is not read up front
+// when the client caches or coalesces requests.
+//
+// The body is read through the browser's ReadableStream, if the browser supports it.
+func (r *Response) Stream() *BodyStream {
+	return &BodyStream{
+		body: r.Body,
+		buf:  make([]byte, streamChunkSize),
+	}
+}
+
+// Next returns the next chunk of the body.
+//
+// The error is io.EOF when the whole body has been read.
+func (s *BodyStream) Next() ([]byte, error) {
+	for {
+		var n, err = s.body.Read(s.buf)
+		if n > 0 {
+			var chunk = make([]byte, n)
+			copy(chunk, s.buf[:n])
+			return chunk, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// Close the stream, cancelling the rest of the body.
+func (s *BodyStream) Close() error {
+	return s.body.Close()
+}
+
+// Lines calls fn for each line of the body, as the lines arrive.
+//
+// Line endings are stripped, returning an error from fn stops reading the body.
+func (r *Response) Lines(fn func(line []byte) error) error {
+	defer r.Body.Close()
+	var reader = bufio.NewReaderSize(r.Body, streamChunkSize)
+	for {
+		var line, err = reader.ReadBytes('\n')
+		if len(line) > 0 {
+			if ferr := fn(bytes.TrimRight(line, "\r\n")); ferr != nil {
+				return ferr
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// DecodeStream decodes each line of the body into a T as it arrives, and calls fn with it.
+//
+// This is useful for newline delimited JSON, empty lines are skipped.
+// If the decoder is nil, JSON is used.
+func DecodeStream[T any](r *Response, d Decoder, fn func(v T) error) error {
+	if d == nil {
+		d = decoder.JSONDecoder
+	}
+	return r.Lines(func(line []byte) error {
+		if len(bytes.TrimSpace(line)) == 0 {
+			return nil
+		}
+		var v T
+		if err := d.DecodeResponse(io.NopCloser(bytes.NewReader(line)), &v); err != nil {
+			return err
+		}
+		return fn(v)
+	})
+}
@@ -0,0 +1,20 @@
+package craterhttp
+
+// Transport sends a single request, and returns its response.
+//
+// Transports are used by a Client after the cache, coalescing and retries have been applied.
+type Transport interface {
+	RoundTrip(r *Request) (*Response, error)
+}
+
+// TransportFunc is a function which implements Transport.
+type TransportFunc func(r *Request) (*Response, error)
+
+func (f TransportFunc) RoundTrip(r *Request) (*Response, error) {
+	return f(r)
+}
+
+// FetchTransport sends requests with the browser's fetch API.
+//
+// XMLHttpRequest is used instead when upload progress is tracked for the request.
+var FetchTransport Transport = TransportFunc(Fetch)
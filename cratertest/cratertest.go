@@ -0,0 +1,460 @@
+// Package cratertest runs crater applications without a browser, so pages can be tested with go test.
+//
+// Tests are compiled to WebAssembly, and run under Node:
+//
+//	GOOS=js GOARCH=wasm go test -exec="$(go env GOROOT)/lib/wasm/go_js_wasm_exec" ./...
+//
+// Importing cratertest installs a minimal DOM when no document is available, see the fakedom package.
+//
+//	func TestHome(t *testing.T) {
+//		var h = cratertest.New(t, nil)
+//		crater.Handle("/", homePage)
+//		crater.Handle("/about", aboutPage)
+//
+//		h.Start("/")
+//		h.AssertContains("Welcome")
+//		h.Follow("a[href='/about']")
+//		h.AssertContains("About us")
+//		h.AssertSignal(crater.SignalPageRendered)
+//	}
+package cratertest
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"syscall/js"
+	"testing"
+	"time"
+
+	// The fake DOM must be installed before jsext is initialized.
+	"github.com/Nigel2392/crater/cratertest/fakedom"
+
+	"github.com/Nigel2392/crater"
+	"github.com/Nigel2392/jsext/v2"
+	"github.com/Nigel2392/jsext/v2/jse"
+)
+
+// The signals recorded by a Harness.
+var Signals = []string{
+	crater.SignalRun,
+	crater.SignalExit,
+	crater.SignalShutdown,
+	crater.SignalPageChange,
+	crater.SignalNavigationCancelled,
+	crater.SignalPageRendered,
+	crater.SignalPageDestroyed,
+	crater.SignalPagePanic,
+	crater.SignalSockConnected,
+	crater.SignalSockClosed,
+	crater.SignalSockStateChange,
+	crater.SignalEventSourceConnected,
+	crater.SignalClientResponse,
+	crater.SignalHandlerAdded,
+}
+
+// Signal is a signal sent by the application.
+type Signal struct {
+	Name  string
+	Value any
+}
+
+// Harness runs a crater application for a test.
+type Harness struct {
+	// How long to wait for pages to render, and for WaitFor conditions.
+	//
+	// If zero, two seconds is used.
+	Timeout time.Duration
+
+	tb      testing.TB
+	mu      sync.Mutex
+	signals []Signal
+	notify  chan struct{}
+	running bool
+}
+
+// New initializes the application with the config, and records the signals it sends.
+//
+// If the config has no root element, the document's body is used.
+// Routes should be added after New, and before Start.
+//
+// The application is shut down when the test finishes, so each test can initialize its own.
+// Tests which use a Harness must not run in parallel.
+func New(tb testing.TB, c *crater.Config) *Harness {
+	tb.Helper()
+	if !fakedom.Installed() {
+		tb.Fatal("cratertest: no document is available")
+	}
+
+	if c == nil {
+		c = &crater.Config{}
+	}
+	if js.Value(c.RootElement).IsUndefined() {
+		c.RootElement = jsext.Body
+	}
+
+	var h = &Harness{
+		tb:     tb,
+		notify: make(chan struct{}),
+	}
+
+	func() {
+		defer func() {
+			if r := recover(); r != nil {
+				tb.Fatalf("cratertest: initializing the application: %v", r)
+			}
+		}()
+		crater.New(c)
+	}()
+	tb.Cleanup(h.shutdown)
+
+	for _, name := range Signals {
+		h.Record(name)
+	}
+	return h
+}
+
+// Record records the signal with the given name, for example a custom hook sent with crater.SendHook.
+func (h *Harness) Record(name string) {
+	crater.RegisterHook(name, func(v any) error {
+		h.mu.Lock()
+		h.signals = append(h.signals, Signal{Name: name, Value: v})
+		close(h.notify)
+		h.notify = make(chan struct{})
+		h.mu.Unlock()
+		return nil
+	})
+}
+
+// Shut down the application, and reset the location for the next test.
+func (h *Harness) shutdown() {
+	var ctx, cancel = context.WithTimeout(context.Background(), h.timeout())
+	defer cancel()
+	if err := crater.Shutdown(ctx); err != nil {
+		h.tb.Errorf("cratertest: shutting down the application: %s", err)
+	}
+	js.Global().Get("history").Call("replaceState", nil, "", "/")
+}
+
+func (h *Harness) timeout() time.Duration {
+	if h.Timeout <= 0 {
+		return 2 * time.Second
+	}
+	return h.Timeout
+}
+
+// Start runs the application, and waits for the page at path to render.
+func (h *Harness) Start(path string) *crater.Page {
+	h.tb.Helper()
+	if h.running {
+		h.tb.Fatal("cratertest: the application is already running")
+	}
+	h.running = true
+
+	js.Global().Get("history").Call("replaceState", nil, "", path)
+	return h.waitForNavigation(path, func() {
+		go crater.Run()
+	})
+}
+
+// Navigate changes the page to path, and waits for it to render.
+//
+// If a navigation guard cancels the navigation, nil is returned.
+func (h *Harness) Navigate(path string) *crater.Page {
+	h.tb.Helper()
+	return h.waitForNavigation(path, func() {
+		crater.HandlePath(path)
+	})
+}
+
+// Follow clicks the element matching the selector, usually a link,
+// and waits for the page it navigates to, to render.
+//
+// Links to paths without a route only render a page if the config has a NotFoundHandler,
+// otherwise the click is ignored and the test fails once the timeout expires, use Click for these.
+func (h *Harness) Follow(selector string) *crater.Page {
+	h.tb.Helper()
+	var e = h.mustQuery(selector)
+	return h.waitForNavigation(selector, func() {
+		e.Call("click")
+	})
+}
+
+// Back navigates back in the history, and waits for the previous page to render.
+func (h *Harness) Back() *crater.Page {
+	h.tb.Helper()
+	return h.waitForNavigation("back", func() {
+		js.Global().Get("history").Call("back")
+	})
+}
+
+// Run f, and wait for the page it navigates to, to render.
+func (h *Harness) waitForNavigation(what string, f func()) *crater.Page {
+	h.tb.Helper()
+	var since = h.count()
+	f()
+	var s, ok = h.waitSignal(since, crater.SignalPageRendered, crater.SignalNavigationCancelled)
+	if !ok {
+		h.tb.Fatalf("cratertest: no page was rendered for %s within %s", what, h.timeout())
+		return nil
+	}
+	if s.Name == crater.SignalNavigationCancelled {
+		return nil
+	}
+	return s.Value.(*crater.Page)
+}
+
+// Page returns the last rendered page, or nil if no page was rendered.
+func (h *Harness) Page() *crater.Page {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for i := len(h.signals) - 1; i >= 0; i-- {
+		if h.signals[i].Name == crater.SignalPageRendered {
+			return h.signals[i].Value.(*crater.Page)
+		}
+	}
+	return nil
+}
+
+// HTML returns the inner HTML of the application's root element.
+func (h *Harness) HTML() string {
+	return crater.Canvas().JSValue().Get("innerHTML").String()
+}
+
+// Text returns the text content of the application's root element.
+func (h *Harness) Text() string {
+	return crater.Canvas().JSValue().Get("textContent").String()
+}
+
+// AssertContains fails the test if the rendered HTML does not contain s.
+func (h *Harness) AssertContains(s string) {
+	h.tb.Helper()
+	if html := h.HTML(); !strings.Contains(html, s) {
+		h.tb.Errorf("expected the page to contain %q, got:\n%s", s, html)
+	}
+}
+
+// AssertNotContains fails the test if the rendered HTML contains s.
+func (h *Harness) AssertNotContains(s string) {
+	h.tb.Helper()
+	if html := h.HTML(); strings.Contains(html, s) {
+		h.tb.Errorf("expected the page not to contain %q, got:\n%s", s, html)
+	}
+}
+
+// Query returns the first element matching the selector, or nil.
+func (h *Harness) Query(selector string) *jse.Element {
+	var v = crater.Canvas().JSValue().Call("querySelector", selector)
+	if v.IsNull() || v.IsUndefined() {
+		return nil
+	}
+	return jse.Make(jsext.Element(v))
+}
+
+// QueryAll returns the elements matching the selector.
+func (h *Harness) QueryAll(selector string) []*jse.Element {
+	var v = crater.Canvas().JSValue().Call("querySelectorAll", selector)
+	var elements = make([]*jse.Element, v.Length())
+	for i := range elements {
+		elements[i] = jse.Make(jsext.Element(v.Index(i)))
+	}
+	return elements
+}
+
+// AssertExists fails the test if no element matches the selector.
+func (h *Harness) AssertExists(selector string) *jse.Element {
+	h.tb.Helper()
+	var e = h.Query(selector)
+	if e == nil {
+		h.tb.Errorf("expected an element matching %q, got:\n%s", selector, h.HTML())
+	}
+	return e
+}
+
+// AssertNotExists fails the test if an element matches the selector.
+func (h *Harness) AssertNotExists(selector string) {
+	h.tb.Helper()
+	if h.Query(selector) != nil {
+		h.tb.Errorf("expected no element matching %q, got:\n%s", selector, h.HTML())
+	}
+}
+
+func (h *Harness) mustQuery(selector string) js.Value {
+	h.tb.Helper()
+	var e = h.Query(selector)
+	if e == nil {
+		h.tb.Fatalf("cratertest: no element matches %q, got:\n%s", selector, h.HTML())
+	}
+	return e.JSValue()
+}
+
+// Click clicks the element matching the selector.
+//
+// Use Follow to wait for the page a link navigates to.
+func (h *Harness) Click(selector string) {
+	h.tb.Helper()
+	h.mustQuery(selector).Call("click")
+}
+
+// Input sets the value of the element matching the selector,
+// and fires the input and change events.
+func (h *Harness) Input(selector, value string) {
+	h.tb.Helper()
+	var e = h.mustQuery(selector)
+	e.Set("value", value)
+	h.dispatch(e, "InputEvent", "input", map[string]any{"data": value, "inputType": "insertText"})
+	h.dispatch(e, "Event", "change", nil)
+}
+
+// Submit submits the form matching the selector.
+func (h *Harness) Submit(selector string) {
+	h.tb.Helper()
+	h.mustQuery(selector).Call("requestSubmit")
+}
+
+// Fire fires a bubbling event of the given type on the element matching the selector.
+func (h *Harness) Fire(selector, event string) {
+	h.tb.Helper()
+	h.dispatch(h.mustQuery(selector), "Event", event, nil)
+}
+
+func (h *Harness) dispatch(e js.Value, constructor, event string, init map[string]any) {
+	var options = map[string]any{
+		"bubbles":    true,
+		"cancelable": true,
+	}
+	for k, v := range init {
+		options[k] = v
+	}
+	e.Call("dispatchEvent", js.Global().Get(constructor).New(event, options))
+}
+
+// WaitFor waits until cond returns true, failing the test after the timeout.
+//
+// This can be used for pages which render asynchronously, like HandleEndpointAsync.
+func (h *Harness) WaitFor(cond func() bool) {
+	h.tb.Helper()
+	var deadline = time.Now().Add(h.timeout())
+	for !cond() {
+		if time.Now().After(deadline) {
+			h.tb.Fatalf("cratertest: condition not met within %s", h.timeout())
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// WaitForSelector waits until an element matches the selector.
+func (h *Harness) WaitForSelector(selector string) *jse.Element {
+	h.tb.Helper()
+	var e *jse.Element
+	h.WaitFor(func() bool {
+		e = h.Query(selector)
+		return e != nil
+	})
+	return e
+}
+
+// Signals returns the values of the recorded signals with the given name.
+func (h *Harness) Signals(name string) []any {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	var values = make([]any, 0)
+	for _, s := range h.signals {
+		if s.Name == name {
+			values = append(values, s.Value)
+		}
+	}
+	return values
+}
+
+// AllSignals returns all recorded signals, in the order they were sent.
+func (h *Harness) AllSignals() []Signal {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	var signals = make([]Signal, len(h.signals))
+	copy(signals, h.signals)
+	return signals
+}
+
+// ClearSignals removes all recorded signals.
+func (h *Harness) ClearSignals() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.signals = nil
+}
+
+// AssertSignal fails the test if the signal was not sent, returning the value it was last sent with.
+func (h *Harness) AssertSignal(name string) any {
+	h.tb.Helper()
+	var values = h.Signals(name)
+	if len(values) == 0 {
+		h.tb.Errorf("expected signal %s to be sent, got: %s", name, h.describeSignals())
+		return nil
+	}
+	return values[len(values)-1]
+}
+
+// AssertNoSignal fails the test if the signal was sent.
+func (h *Harness) AssertNoSignal(name string) {
+	h.tb.Helper()
+	if values := h.Signals(name); len(values) > 0 {
+		h.tb.Errorf("expected signal %s not to be sent, it was sent %d times", name, len(values))
+	}
+}
+
+// WaitSignal waits for the signal to be sent, it returns immediately if it was already recorded.
+func (h *Harness) WaitSignal(name string) any {
+	h.tb.Helper()
+	var s, ok = h.waitSignal(0, name)
+	if !ok {
+		h.tb.Fatalf("cratertest: signal %s was not sent within %s", name, h.timeout())
+		return nil
+	}
+	return s.Value
+}
+
+func (h *Harness) count() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.signals)
+}
+
+// Wait for one of the signals to be recorded after the first since signals.
+func (h *Harness) waitSignal(since int, names ...string) (Signal, bool) {
+	var timeout = time.NewTimer(h.timeout())
+	defer timeout.Stop()
+	for {
+		h.mu.Lock()
+		for i := since; i < len(h.signals); i++ {
+			for _, name := range names {
+				if h.signals[i].Name == name {
+					var s = h.signals[i]
+					h.mu.Unlock()
+					return s, true
+				}
+			}
+		}
+		since = len(h.signals)
+		var notify = h.notify
+		h.mu.Unlock()
+
+		select {
+		case <-notify:
+		case <-timeout.C:
+			return Signal{}, false
+		}
+	}
+}
+
+func (h *Harness) describeSignals() string {
+	var signals = h.AllSignals()
+	if len(signals) == 0 {
+		return "no signals"
+	}
+	var names = make([]string, len(signals))
+	for i, s := range signals {
+		names[i] = s.Name
+	}
+	return fmt.Sprintf("[%s]", strings.Join(names, ", "))
+}
@@ -0,0 +1,142 @@
+package cratertest_test
+
+import (
+	"strings"
+	"syscall/js"
+	"testing"
+
+	"github.com/Nigel2392/crater"
+	"github.com/Nigel2392/crater/cratertest"
+	"github.com/Nigel2392/jsext/v2/jse"
+)
+
+func handlePages() {
+	crater.Handle("/", crater.ToPageFunc(func(p *crater.Page) {
+		p.AppendChild(jse.Heading(1, "Welcome"), jse.A("/about", "About"), jse.A("/missing", "Missing"))
+	}))
+	crater.Handle("/about", crater.ToPageFunc(func(p *crater.Page) {
+		p.AppendChild(jse.Heading(1, "About us"))
+	}))
+}
+
+func TestFollow(t *testing.T) {
+	var h = cratertest.New(t, nil)
+	handlePages()
+
+	h.Start("/")
+	h.AssertContains("Welcome")
+
+	var page = h.Follow("a[href='/about']")
+	if page == nil || crater.PathFromContext(page.Context) != "/about" {
+		t.Fatalf("expected the about page to render, got %v", page)
+	}
+	h.AssertContains("About us")
+	h.AssertNotContains("Welcome")
+
+	h.Back()
+	h.AssertContains("Welcome")
+}
+
+func TestNotFound(t *testing.T) {
+	var h = cratertest.New(t, &crater.Config{
+		NotFoundHandler: crater.ToPageFunc(func(p *crater.Page) {
+			p.AppendChild(jse.P("Page not found"))
+		}),
+	})
+	handlePages()
+
+	h.Start("/")
+	h.Follow("a[href='/missing']")
+	h.AssertContains("Page not found")
+}
+
+func TestNotFoundWithoutHandler(t *testing.T) {
+	var h = cratertest.New(t, nil)
+	handlePages()
+
+	h.Start("/")
+	h.Click("a[href='/missing']")
+	h.AssertContains("Welcome")
+
+	// The application still handles links after the click.
+	h.Follow("a[href='/about']")
+	h.AssertContains("About us")
+}
+
+func location() string {
+	var l = js.Global().Get("location")
+	return l.Get("pathname").String() + l.Get("search").String()
+}
+
+func TestGuardCancel(t *testing.T) {
+	var h = cratertest.New(t, nil)
+	handlePages()
+	var allow bool
+	crater.BeforeEnter(func(n *crater.Navigation) error {
+		if n.To == "/about" && !allow {
+			return crater.ErrNavigationCancelled
+		}
+		return nil
+	})
+
+	var home = h.Start("/?tab=1")
+	if page := h.Navigate("/about"); page != nil {
+		t.Fatalf("expected the navigation to be cancelled, got %v", page)
+	}
+	var nav = h.AssertSignal(crater.SignalNavigationCancelled).(*crater.Navigation)
+	if nav.From != "/?tab=1" || nav.To != "/about" {
+		t.Errorf("expected a navigation from /?tab=1 to /about, got %s to %s", nav.From, nav.To)
+	}
+	h.WaitFor(func() bool { return location() == "/?tab=1" })
+	h.AssertContains("Welcome")
+	if h.Page() != home {
+		t.Error("expected the previous page to be kept")
+	}
+
+	// The same link is handled again once the guard allows it.
+	allow = true
+	h.Follow("a[href='/about']")
+	h.AssertContains("About us")
+}
+
+func TestGuardCancelBack(t *testing.T) {
+	var h = cratertest.New(t, nil)
+	handlePages()
+	crater.BeforeLeave(func(n *crater.Navigation) error {
+		if n.From == "/about" {
+			return crater.ErrNavigationCancelled
+		}
+		return nil
+	})
+
+	h.Start("/")
+	h.Follow("a[href='/about']")
+	if page := h.Back(); page != nil {
+		t.Fatalf("expected the navigation to be cancelled, got %v", page)
+	}
+	h.WaitFor(func() bool { return location() == "/about" })
+	h.AssertContains("About us")
+	if history := js.Global().Get("history").Get("length").Int(); history < 2 {
+		t.Errorf("expected the history entries to be kept, got %d", history)
+	}
+}
+
+func TestGuardRedirect(t *testing.T) {
+	var h = cratertest.New(t, nil)
+	handlePages()
+	crater.Handle("/login", crater.ToPageFunc(func(p *crater.Page) {
+		p.AppendChild(jse.Heading(1, "Log in"))
+	}))
+	crater.BeforeEnter(func(n *crater.Navigation) error {
+		if n.To == "/about" {
+			n.Redirect("/login")
+		}
+		return nil
+	})
+
+	h.Start("/")
+	h.Click("a[href='/about']")
+	h.WaitFor(func() bool { return strings.Contains(h.HTML(), "Log in") })
+	h.WaitFor(func() bool { return location() == "/login" })
+	h.AssertSignal(crater.SignalNavigationCancelled)
+}
@@ -0,0 +1,38 @@
+// Package fakedom installs a minimal DOM when the application runs without a browser,
+// for example when tests are run under Node with go_js_wasm_exec.
+//
+// jsext reads the document when it is initialized, so the DOM must exist before that.
+// Importing this package before any package which imports jsext takes care of this,
+// cratertest does so. If another package is initialized first, preload the script instead:
+//
+//	NODE_OPTIONS="--require=/path/to/cratertest/fakedom/fakedom.js" go test ./...
+package fakedom
+
+import (
+	_ "embed"
+	"syscall/js"
+)
+
+//go:embed fakedom.js
+var script string
+
+// Script returns the javascript source of the fake DOM.
+func Script() string {
+	return script
+}
+
+// Installed reports whether a document is available.
+func Installed() bool {
+	return !js.Global().Get("document").IsUndefined()
+}
+
+// Install the fake DOM, if no document is available.
+func Install() {
+	if !Installed() {
+		js.Global().Call("eval", script)
+	}
+}
+
+func init() {
+	Install()
+}
@@ -0,0 +1,1113 @@
+// A minimal DOM for running crater applications under Node.
+//
+// Only the parts of the DOM used by crater, jsext and mux are implemented.
+// The DOM is only installed if document is undefined, so this file can also be
+// preloaded with: NODE_OPTIONS="--require=/path/to/fakedom.js"
+(function (g) {
+	"use strict";
+	if (typeof g.document !== "undefined") {
+		return;
+	}
+
+	const VOID_ELEMENTS = new Set([
+		"area", "base", "br", "col", "embed", "hr", "img", "input",
+		"link", "meta", "param", "source", "track", "wbr",
+	]);
+	const RAW_TEXT_ELEMENTS = new Set(["script", "style", "textarea", "title"]);
+
+	function escapeText(s) {
+		return String(s).replace(/&/g, "&amp;").replace(/</g, "&lt;").replace(/>/g, "&gt;");
+	}
+
+	function escapeAttr(s) {
+		return String(s).replace(/&/g, "&amp;").replace(/"/g, "&quot;");
+	}
+
+	function decodeEntities(s) {
+		return s.replace(/&(#x[0-9a-fA-F]+|#[0-9]+|amp|lt|gt|quot|apos|nbsp);/g, function (m, e) {
+			switch (e) {
+				case "amp": return "&";
+				case "lt": return "<";
+				case "gt": return ">";
+				case "quot": return "\"";
+				case "apos": return "'";
+				case "nbsp": return " ";
+			}
+			if (e[1] === "x") {
+				return String.fromCodePoint(parseInt(e.slice(2), 16));
+			}
+			return String.fromCodePoint(parseInt(e.slice(1), 10));
+		});
+	}
+
+	function kebabCase(s) {
+		return s.replace(/[A-Z]/g, function (c) { return "-" + c.toLowerCase(); });
+	}
+
+	function camelCase(s) {
+		return s.replace(/-([a-z])/g, function (m, c) { return c.toUpperCase(); });
+	}
+
+	// Events
+
+	function defineEventProperty(event, name, value) {
+		Object.defineProperty(event, name, { value: value, configurable: true, writable: true });
+	}
+
+	function makeEventClass(name, fields) {
+		return class extends g.Event {
+			constructor(type, init) {
+				init = init || {};
+				super(type, init);
+				for (const [key, def] of Object.entries(fields)) {
+					this[key] = key in init ? init[key] : def;
+				}
+			}
+		};
+	}
+
+	const eventClasses = {
+		UIEvent: { detail: 0, view: null },
+		MouseEvent: { button: 0, buttons: 0, clientX: 0, clientY: 0, altKey: false, ctrlKey: false, metaKey: false, shiftKey: false },
+		KeyboardEvent: { key: "", code: "", altKey: false, ctrlKey: false, metaKey: false, shiftKey: false, repeat: false },
+		InputEvent: { data: null, inputType: "" },
+		FocusEvent: { relatedTarget: null },
+		SubmitEvent: { submitter: null },
+		PopStateEvent: { state: null },
+	};
+	for (const [name, fields] of Object.entries(eventClasses)) {
+		if (typeof g[name] === "undefined") {
+			g[name] = makeEventClass(name, fields);
+		}
+	}
+
+	// Nodes
+
+	class FakeNode {
+		constructor(nodeType, nodeName) {
+			this.nodeType = nodeType;
+			this.nodeName = nodeName;
+			this.childNodes = [];
+			this.parentNode = null;
+			this._listeners = {};
+		}
+
+		get ownerDocument() { return g.document; }
+		get parentElement() { return this.parentNode && this.parentNode.nodeType === 1 ? this.parentNode : null; }
+		get firstChild() { return this.childNodes[0] || null; }
+		get lastChild() { return this.childNodes[this.childNodes.length - 1] || null; }
+		get nextSibling() { return this._sibling(1); }
+		get previousSibling() { return this._sibling(-1); }
+		get isConnected() {
+			let node = this;
+			while (node.parentNode) {
+				node = node.parentNode;
+			}
+			return node === g.document;
+		}
+
+		_sibling(offset) {
+			if (!this.parentNode) {
+				return null;
+			}
+			const siblings = this.parentNode.childNodes;
+			return siblings[siblings.indexOf(this) + offset] || null;
+		}
+
+		hasChildNodes() { return this.childNodes.length > 0; }
+
+		contains(node) {
+			while (node) {
+				if (node === this) {
+					return true;
+				}
+				node = node.parentNode;
+			}
+			return false;
+		}
+
+		_adopt(node) {
+			if (node.nodeType === 11) {
+				const children = node.childNodes.slice();
+				node.childNodes = [];
+				for (const child of children) {
+					child.parentNode = null;
+				}
+				return children;
+			}
+			if (node.parentNode) {
+				node.parentNode.removeChild(node);
+			}
+			return [node];
+		}
+
+		appendChild(node) {
+			for (const child of this._adopt(node)) {
+				child.parentNode = this;
+				this.childNodes.push(child);
+			}
+			return node;
+		}
+
+		insertBefore(node, ref) {
+			if (!ref) {
+				return this.appendChild(node);
+			}
+			const children = this._adopt(node);
+			const index = this.childNodes.indexOf(ref);
+			if (index === -1) {
+				throw new Error("NotFoundError: the reference node is not a child of this node");
+			}
+			for (const child of children) {
+				child.parentNode = this;
+			}
+			this.childNodes.splice(index, 0, ...children);
+			return node;
+		}
+
+		removeChild(node) {
+			const index = this.childNodes.indexOf(node);
+			if (index === -1) {
+				throw new Error("NotFoundError: the node is not a child of this node");
+			}
+			this.childNodes.splice(index, 1);
+			node.parentNode = null;
+			return node;
+		}
+
+		replaceChild(node, old) {
+			this.insertBefore(node, old);
+			if (old.parentNode === this) {
+				this.removeChild(old);
+			}
+			return old;
+		}
+
+		_toNodes(nodes) {
+			return nodes.map(function (n) {
+				return n instanceof FakeNode ? n : g.document.createTextNode(String(n));
+			});
+		}
+
+		append(...nodes) {
+			for (const node of this._toNodes(nodes)) {
+				this.appendChild(node);
+			}
+		}
+
+		prepend(...nodes) {
+			const first = this.firstChild;
+			for (const node of this._toNodes(nodes)) {
+				this.insertBefore(node, first);
+			}
+		}
+
+		before(...nodes) {
+			if (this.parentNode) {
+				for (const node of this._toNodes(nodes)) {
+					this.parentNode.insertBefore(node, this);
+				}
+			}
+		}
+
+		after(...nodes) {
+			if (this.parentNode) {
+				const next = this.nextSibling;
+				for (const node of this._toNodes(nodes)) {
+					this.parentNode.insertBefore(node, next);
+				}
+			}
+		}
+
+		replaceWith(...nodes) {
+			if (this.parentNode) {
+				const parent = this.parentNode;
+				const next = this.nextSibling;
+				parent.removeChild(this);
+				for (const node of this._toNodes(nodes)) {
+					parent.insertBefore(node, next);
+				}
+			}
+		}
+
+		remove() {
+			if (this.parentNode) {
+				this.parentNode.removeChild(this);
+			}
+		}
+
+		get textContent() {
+			return this.childNodes.map(function (n) { return n.textContent; }).join("");
+		}
+
+		set textContent(value) {
+			for (const child of this.childNodes) {
+				child.parentNode = null;
+			}
+			this.childNodes = [];
+			if (value !== null && value !== undefined && value !== "") {
+				this.appendChild(g.document.createTextNode(String(value)));
+			}
+		}
+
+		addEventListener(type, listener, options) {
+			if (!listener) {
+				return;
+			}
+			const listeners = this._listeners[type] || (this._listeners[type] = []);
+			const capture = typeof options === "boolean" ? options : !!(options && options.capture);
+			if (listeners.some(function (l) { return l.listener === listener && l.capture === capture; })) {
+				return;
+			}
+			listeners.push({ listener: listener, capture: capture, once: !!(options && options.once) });
+		}
+
+		removeEventListener(type, listener, options) {
+			const listeners = this._listeners[type];
+			if (!listeners) {
+				return;
+			}
+			const capture = typeof options === "boolean" ? options : !!(options && options.capture);
+			this._listeners[type] = listeners.filter(function (l) {
+				return l.listener !== listener || l.capture !== capture;
+			});
+		}
+
+		_invoke(event, capture) {
+			const listeners = (this._listeners[event.type] || []).slice();
+			for (const l of listeners) {
+				if (l.capture !== capture && event.eventPhase !== 2) {
+					continue;
+				}
+				if (l.once) {
+					this.removeEventListener(event.type, l.listener, l.capture);
+				}
+				if (typeof l.listener === "function") {
+					l.listener.call(this, event);
+				} else if (l.listener && typeof l.listener.handleEvent === "function") {
+					l.listener.handleEvent(event);
+				}
+				if (event._stopImmediate) {
+					return;
+				}
+			}
+			const handler = this["on" + event.type];
+			if (typeof handler === "function" && event.eventPhase !== 1) {
+				handler.call(this, event);
+			}
+		}
+
+		dispatchEvent(event) {
+			const path = [];
+			for (let node = this; node; node = node.parentNode) {
+				path.push(node);
+			}
+			if (path[path.length - 1] === g.document) {
+				path.push(windowTarget);
+			}
+
+			const stopPropagation = event.stopPropagation;
+			const stopImmediatePropagation = event.stopImmediatePropagation;
+			defineEventProperty(event, "_stopped", false);
+			defineEventProperty(event, "_stopImmediate", false);
+			defineEventProperty(event, "stopPropagation", function () {
+				event._stopped = true;
+				stopPropagation.call(event);
+			});
+			defineEventProperty(event, "stopImmediatePropagation", function () {
+				event._stopped = true;
+				event._stopImmediate = true;
+				stopImmediatePropagation.call(event);
+			});
+			defineEventProperty(event, "target", this);
+			defineEventProperty(event, "srcElement", this);
+			defineEventProperty(event, "composedPath", function () { return path.slice(); });
+
+			// Capturing phase.
+			for (let i = path.length - 1; i > 0 && !event._stopped; i--) {
+				defineEventProperty(event, "currentTarget", path[i]);
+				defineEventProperty(event, "eventPhase", 1);
+				path[i]._invoke(event, true);
+			}
+			// Target phase.
+			if (!event._stopped) {
+				defineEventProperty(event, "currentTarget", this);
+				defineEventProperty(event, "eventPhase", 2);
+				this._invoke(event, false);
+			}
+			// Bubbling phase.
+			if (event.bubbles) {
+				for (let i = 1; i < path.length && !event._stopped; i++) {
+					defineEventProperty(event, "currentTarget", path[i]);
+					defineEventProperty(event, "eventPhase", 3);
+					path[i]._invoke(event, false);
+				}
+			}
+			defineEventProperty(event, "currentTarget", null);
+			defineEventProperty(event, "eventPhase", 0);
+			return !event.defaultPrevented;
+		}
+	}
+
+	class FakeText extends FakeNode {
+		constructor(data) {
+			super(3, "#text");
+			this.data = data;
+		}
+		get nodeValue() { return this.data; }
+		set nodeValue(v) { this.data = String(v); }
+		get textContent() { return this.data; }
+		set textContent(v) { this.data = String(v); }
+		get outerHTML() { return escapeText(this.data); }
+		cloneNode() { return new FakeText(this.data); }
+	}
+
+	class FakeComment extends FakeNode {
+		constructor(data) {
+			super(8, "#comment");
+			this.data = data;
+		}
+		get textContent() { return ""; }
+		set textContent(v) { this.data = String(v); }
+		get outerHTML() { return "<!--" + this.data + "-->"; }
+		cloneNode() { return new FakeComment(this.data); }
+	}
+
+	class FakeFragment extends FakeNode {
+		constructor() {
+			super(11, "#document-fragment");
+		}
+	}
+
+	class ClassList {
+		constructor(element) {
+			this._element = element;
+		}
+		_get() {
+			const value = this._element.getAttribute("class");
+			return value ? value.split(/\s+/).filter(Boolean) : [];
+		}
+		_set(classes) {
+			this._element.setAttribute("class", classes.join(" "));
+		}
+		get length() { return this._get().length; }
+		get value() { return this._get().join(" "); }
+		item(i) { return this._get()[i] || null; }
+		contains(c) { return this._get().includes(c); }
+		add(...classes) {
+			const current = this._get();
+			for (const c of classes) {
+				if (c && !current.includes(c)) {
+					current.push(c);
+				}
+			}
+			this._set(current);
+		}
+		remove(...classes) {
+			this._set(this._get().filter(function (c) { return !classes.includes(c); }));
+		}
+		toggle(c, force) {
+			const has = this.contains(c);
+			const add = force === undefined ? !has : !!force;
+			if (add) {
+				this.add(c);
+			} else {
+				this.remove(c);
+			}
+			return add;
+		}
+		replace(old, c) {
+			if (!this.contains(old)) {
+				return false;
+			}
+			this._set(this._get().map(function (x) { return x === old ? c : x; }));
+			return true;
+		}
+		forEach(fn) { this._get().forEach(fn); }
+		toString() { return this.value; }
+	}
+
+	function makeStyle() {
+		const style = {};
+		Object.defineProperties(style, {
+			setProperty: { value: function (name, value) { style[camelCase(name)] = String(value); } },
+			getPropertyValue: { value: function (name) { return style[camelCase(name)] || ""; } },
+			removeProperty: { value: function (name) { delete style[camelCase(name)]; } },
+			cssText: {
+				get: function () {
+					return Object.keys(style)
+						.filter(function (k) { return style[k] !== "" && style[k] !== null && style[k] !== undefined; })
+						.map(function (k) { return kebabCase(k) + ": " + style[k] + ";"; })
+						.join(" ");
+				},
+				set: function (text) {
+					for (const k of Object.keys(style)) {
+						delete style[k];
+					}
+					for (const decl of String(text).split(";")) {
+						const i = decl.indexOf(":");
+						if (i > 0) {
+							style[camelCase(decl.slice(0, i).trim())] = decl.slice(i + 1).trim();
+						}
+					}
+				},
+			},
+		});
+		return style;
+	}
+
+	class FakeElement extends FakeNode {
+		constructor(tag, namespaceURI) {
+			const isHTML = !namespaceURI || namespaceURI === "http://www.w3.org/1999/xhtml";
+			super(1, isHTML ? tag.toUpperCase() : tag);
+			this.tagName = this.nodeName;
+			this.localName = isHTML ? tag.toLowerCase() : tag;
+			this.namespaceURI = namespaceURI || "http://www.w3.org/1999/xhtml";
+			this._attrs = new Map();
+			this.style = makeStyle();
+			this.classList = new ClassList(this);
+			this.dataset = this._makeDataset();
+			this._value = undefined;
+			this.checked = false;
+			this.disabled = false;
+			this.selected = false;
+			this.scrollTop = 0;
+			this.scrollLeft = 0;
+		}
+
+		_makeDataset() {
+			const element = this;
+			return new Proxy({}, {
+				get: function (t, key) {
+					return typeof key === "string" ? (element.getAttribute("data-" + kebabCase(key)) ?? undefined) : undefined;
+				},
+				set: function (t, key, value) {
+					element.setAttribute("data-" + kebabCase(key), value);
+					return true;
+				},
+				deleteProperty: function (t, key) {
+					element.removeAttribute("data-" + kebabCase(key));
+					return true;
+				},
+				has: function (t, key) {
+					return element.hasAttribute("data-" + kebabCase(String(key)));
+				},
+				ownKeys: function () {
+					return Array.from(element._attrs.keys())
+						.filter(function (k) { return k.startsWith("data-"); })
+						.map(function (k) { return camelCase(k.slice(5)); });
+				},
+				getOwnPropertyDescriptor: function (t, key) {
+					return { enumerable: true, configurable: true, value: element.getAttribute("data-" + kebabCase(String(key))) };
+				},
+			});
+		}
+
+		get id() { return this.getAttribute("id") || ""; }
+		set id(v) { this.setAttribute("id", v); }
+		get className() { return this.getAttribute("class") || ""; }
+		set className(v) { this.setAttribute("class", v); }
+		get href() { return this.getAttribute("href") || ""; }
+		set href(v) { this.setAttribute("href", v); }
+		get name() { return this.getAttribute("name") || ""; }
+		set name(v) { this.setAttribute("name", v); }
+		get type() { return this.getAttribute("type") || (this.localName === "button" ? "submit" : this.localName === "input" ? "text" : ""); }
+		set type(v) { this.setAttribute("type", v); }
+		get title() { return this.getAttribute("title") || ""; }
+		set title(v) { this.setAttribute("title", v); }
+		get src() { return this.getAttribute("src") || ""; }
+		set src(v) { this.setAttribute("src", v); }
+		get value() {
+			if (this._value !== undefined) {
+				return this._value;
+			}
+			if (this.localName === "textarea") {
+				return this.textContent;
+			}
+			if (this.localName === "select") {
+				const option = this.querySelectorAll("option").find(function (o) { return o.selected; }) || this.querySelector("option");
+				return option ? option.value : "";
+			}
+			return this.getAttribute("value") || "";
+		}
+		set value(v) { this._value = String(v); }
+		get form() {
+			let node = this.parentNode;
+			while (node && node.localName !== "form") {
+				node = node.parentNode;
+			}
+			return node || null;
+		}
+		get elements() {
+			return this.querySelectorAll("input, select, textarea, button");
+		}
+
+		get attributes() {
+			return Array.from(this._attrs, function ([name, value]) { return { name: name, value: value }; });
+		}
+		getAttributeNames() { return Array.from(this._attrs.keys()); }
+		hasAttribute(name) { return this._attrs.has(String(name).toLowerCase()); }
+		getAttribute(name) {
+			const value = this._attrs.get(String(name).toLowerCase());
+			return value === undefined ? null : value;
+		}
+		setAttribute(name, value) { this._attrs.set(String(name).toLowerCase(), String(value)); }
+		removeAttribute(name) { this._attrs.delete(String(name).toLowerCase()); }
+		toggleAttribute(name, force) {
+			const add = force === undefined ? !this.hasAttribute(name) : !!force;
+			if (add) {
+				this.setAttribute(name, "");
+			} else {
+				this.removeAttribute(name);
+			}
+			return add;
+		}
+		getAttributeNS(ns, name) { return this.getAttribute(name); }
+		setAttributeNS(ns, name, value) { this.setAttribute(name, value); }
+		removeAttributeNS(ns, name) { this.removeAttribute(name); }
+
+		get children() { return this.childNodes.filter(function (n) { return n.nodeType === 1; }); }
+		get childElementCount() { return this.children.length; }
+		get firstElementChild() { return this.children[0] || null; }
+		get lastElementChild() { const c = this.children; return c[c.length - 1] || null; }
+		get nextElementSibling() {
+			let node = this.nextSibling;
+			while (node && node.nodeType !== 1) {
+				node = node.nextSibling;
+			}
+			return node;
+		}
+		get previousElementSibling() {
+			let node = this.previousSibling;
+			while (node && node.nodeType !== 1) {
+				node = node.previousSibling;
+			}
+			return node;
+		}
+
+		_attributesHTML() {
+			const attrs = new Map(this._attrs);
+			const css = this.style.cssText;
+			if (css) {
+				attrs.set("style", css);
+			}
+			let html = "";
+			for (const [name, value] of attrs) {
+				html += " " + name + "=\"" + escapeAttr(value) + "\"";
+			}
+			return html;
+		}
+
+		get innerHTML() {
+			if (RAW_TEXT_ELEMENTS.has(this.localName)) {
+				return this.textContent;
+			}
+			return this.childNodes.map(function (n) { return n.outerHTML; }).join("");
+		}
+		set innerHTML(html) {
+			for (const child of this.childNodes) {
+				child.parentNode = null;
+			}
+			this.childNodes = [];
+			if (RAW_TEXT_ELEMENTS.has(this.localName)) {
+				this.textContent = html;
+				return;
+			}
+			for (const node of parseHTML(String(html ?? ""))) {
+				this.appendChild(node);
+			}
+		}
+		get outerHTML() {
+			const open = "<" + this.localName + this._attributesHTML() + ">";
+			if (VOID_ELEMENTS.has(this.localName)) {
+				return open;
+			}
+			return open + this.innerHTML + "</" + this.localName + ">";
+		}
+		get innerText() { return this.textContent; }
+		set innerText(v) { this.textContent = v; }
+
+		insertAdjacentHTML(position, html) {
+			const nodes = parseHTML(String(html));
+			switch (position.toLowerCase()) {
+				case "beforebegin": this.before(...nodes); break;
+				case "afterbegin": this.prepend(...nodes); break;
+				case "beforeend": this.append(...nodes); break;
+				case "afterend": this.after(...nodes); break;
+			}
+		}
+		insertAdjacentElement(position, element) {
+			this.insertAdjacentHTML(position, "");
+			switch (position.toLowerCase()) {
+				case "beforebegin": this.before(element); break;
+				case "afterbegin": this.prepend(element); break;
+				case "beforeend": this.append(element); break;
+				case "afterend": this.after(element); break;
+			}
+			return element;
+		}
+
+		cloneNode(deep) {
+			const clone = new FakeElement(this.localName, this.namespaceURI);
+			for (const [name, value] of this._attrs) {
+				clone._attrs.set(name, value);
+			}
+			clone.style.cssText = this.style.cssText;
+			if (deep) {
+				for (const child of this.childNodes) {
+					clone.appendChild(child.cloneNode(true));
+				}
+			}
+			return clone;
+		}
+
+		matches(selector) { return matchesSelectorList(this, selector); }
+		closest(selector) {
+			for (let node = this; node && node.nodeType === 1; node = node.parentNode) {
+				if (node.matches(selector)) {
+					return node;
+				}
+			}
+			return null;
+		}
+		querySelectorAll(selector) { return descendants(this).filter(function (e) { return e.matches(selector); }); }
+		querySelector(selector) { return this.querySelectorAll(selector)[0] || null; }
+		getElementsByTagName(tag) {
+			tag = tag.toLowerCase();
+			return descendants(this).filter(function (e) { return tag === "*" || e.localName === tag; });
+		}
+		getElementsByClassName(names) {
+			const classes = names.split(/\s+/).filter(Boolean);
+			return descendants(this).filter(function (e) {
+				return classes.every(function (c) { return e.classList.contains(c); });
+			});
+		}
+
+		click() {
+			if (this.disabled) {
+				return;
+			}
+			if (this.localName === "input" && (this.type === "checkbox" || this.type === "radio")) {
+				this.checked = this.type === "radio" ? true : !this.checked;
+			}
+			const event = new g.MouseEvent("click", { bubbles: true, cancelable: true, button: 0 });
+			const notCancelled = this.dispatchEvent(event);
+			if (notCancelled && this.type === "submit" && this.form) {
+				this.form.requestSubmit(this);
+			}
+		}
+		focus() { g.document.activeElement = this; }
+		blur() {
+			if (g.document.activeElement === this) {
+				g.document.activeElement = g.document.body;
+			}
+		}
+		submit() { }
+		requestSubmit(submitter) {
+			const event = new g.SubmitEvent("submit", { bubbles: true, cancelable: true, submitter: submitter || null });
+			this.dispatchEvent(event);
+		}
+		reset() { }
+		checkValidity() { return true; }
+		reportValidity() { return true; }
+
+		get clientWidth() { return 0; }
+		get clientHeight() { return 0; }
+		get clientTop() { return 0; }
+		get clientLeft() { return 0; }
+		get offsetWidth() { return 0; }
+		get offsetHeight() { return 0; }
+		get offsetTop() { return 0; }
+		get offsetLeft() { return 0; }
+		get scrollWidth() { return 0; }
+		get scrollHeight() { return 0; }
+		getBoundingClientRect() {
+			return { x: 0, y: 0, top: 0, left: 0, right: 0, bottom: 0, width: 0, height: 0 };
+		}
+		getClientRects() { return []; }
+		scrollTo() { }
+		scrollBy() { }
+		scrollIntoView() { }
+		scrollIntoViewIfNeeded() { }
+		animate() {
+			return { finished: Promise.resolve(), cancel: function () { }, play: function () { }, pause: function () { }, finish: function () { } };
+		}
+	}
+
+	function descendants(node) {
+		const result = [];
+		const walk = function (n) {
+			for (const child of n.childNodes) {
+				if (child.nodeType === 1) {
+					result.push(child);
+					walk(child);
+				}
+			}
+		};
+		walk(node);
+		return result;
+	}
+
+	// Selectors: type, #id, .class, [attr], [attr=value], [attr^=value], [attr$=value],
+	// [attr*=value], :first-child, :last-child, :checked, :disabled and the descendant and child combinators.
+
+	function parseCompound(s) {
+		const parts = { tag: null, id: null, classes: [], attrs: [], pseudos: [] };
+		const re = /^(\*|[a-zA-Z][\w-]*)|#([\w-]+)|\.([\w-]+)|\[\s*([\w-:]+)\s*(?:([\^$*~|]?=)\s*(?:"([^"]*)"|'([^']*)'|([^\]\s]*))\s*)?\]|:([\w-]+)/g;
+		let match;
+		let consumed = 0;
+		while ((match = re.exec(s)) !== null) {
+			if (match.index !== consumed) {
+				throw new Error("SyntaxError: unsupported selector: " + s);
+			}
+			consumed = re.lastIndex;
+			if (match[1]) {
+				parts.tag = match[1].toLowerCase();
+			} else if (match[2]) {
+				parts.id = match[2];
+			} else if (match[3]) {
+				parts.classes.push(match[3]);
+			} else if (match[4]) {
+				parts.attrs.push({ name: match[4].toLowerCase(), op: match[5], value: match[6] ?? match[7] ?? match[8] });
+			} else if (match[9]) {
+				parts.pseudos.push(match[9]);
+			}
+		}
+		if (consumed !== s.length) {
+			throw new Error("SyntaxError: unsupported selector: " + s);
+		}
+		return parts;
+	}
+
+	function matchesCompound(e, parts) {
+		if (parts.tag && parts.tag !== "*" && e.localName !== parts.tag) {
+			return false;
+		}
+		if (parts.id && e.id !== parts.id) {
+			return false;
+		}
+		for (const c of parts.classes) {
+			if (!e.classList.contains(c)) {
+				return false;
+			}
+		}
+		for (const a of parts.attrs) {
+			const value = e.getAttribute(a.name);
+			if (value === null) {
+				return false;
+			}
+			switch (a.op) {
+				case "=": if (value !== a.value) return false; break;
+				case "^=": if (!value.startsWith(a.value)) return false; break;
+				case "$=": if (!value.endsWith(a.value)) return false; break;
+				case "*=": if (!value.includes(a.value)) return false; break;
+				case "~=": if (!value.split(/\s+/).includes(a.value)) return false; break;
+				case "|=": if (value !== a.value && !value.startsWith(a.value + "-")) return false; break;
+			}
+		}
+		for (const p of parts.pseudos) {
+			switch (p) {
+				case "first-child": if (e.parentNode && e.parentNode.children[0] !== e) return false; break;
+				case "last-child": {
+					const siblings = e.parentNode ? e.parentNode.children : [e];
+					if (siblings[siblings.length - 1] !== e) return false;
+					break;
+				}
+				case "checked": if (!e.checked && !e.selected) return false; break;
+				case "disabled": if (!e.disabled && !e.hasAttribute("disabled")) return false; break;
+				default: throw new Error("SyntaxError: unsupported pseudo-class: " + p);
+			}
+		}
+		return true;
+	}
+
+	function parseComplex(selector) {
+		const tokens = selector.trim().replace(/\s*>\s*/g, " > ").split(/\s+/);
+		const steps = [];
+		let combinator = " ";
+		for (const token of tokens) {
+			if (token === ">") {
+				combinator = ">";
+				continue;
+			}
+			steps.push({ combinator: combinator, parts: parseCompound(token) });
+			combinator = " ";
+		}
+		return steps;
+	}
+
+	function matchesSteps(e, steps, i) {
+		if (!matchesCompound(e, steps[i].parts)) {
+			return false;
+		}
+		if (i === 0) {
+			return true;
+		}
+		if (steps[i].combinator === ">") {
+			const parent = e.parentElement;
+			return !!parent && matchesSteps(parent, steps, i - 1);
+		}
+		for (let parent = e.parentElement; parent; parent = parent.parentElement) {
+			if (matchesSteps(parent, steps, i - 1)) {
+				return true;
+			}
+		}
+		return false;
+	}
+
+	function matchesSelectorList(e, selector) {
+		return selector.split(",").some(function (s) {
+			const steps = parseComplex(s);
+			return matchesSteps(e, steps, steps.length - 1);
+		});
+	}
+
+	// A forgiving HTML parser, enough for the markup applications render.
+	function parseHTML(html) {
+		const root = new FakeFragment();
+		const stack = [root];
+		const current = function () { return stack[stack.length - 1]; };
+		const tagRe = /<!--([\s\S]*?)-->|<\/\s*([a-zA-Z][\w:-]*)\s*>|<([a-zA-Z][\w:-]*)((?:\s+[^\s"'>\/=]+(?:\s*=\s*(?:"[^"]*"|'[^']*'|[^\s>]+))?)*)\s*(\/?)>/g;
+		const attrRe = /([^\s"'>\/=]+)(?:\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s>]+)))?/g;
+		let last = 0;
+		let match;
+		while ((match = tagRe.exec(html)) !== null) {
+			if (match.index > last) {
+				current().appendChild(new FakeText(decodeEntities(html.slice(last, match.index))));
+			}
+			last = tagRe.lastIndex;
+			if (match[1] !== undefined) {
+				current().appendChild(new FakeComment(match[1]));
+			} else if (match[2]) {
+				const tag = match[2].toLowerCase();
+				for (let i = stack.length - 1; i > 0; i--) {
+					if (stack[i].localName === tag) {
+						stack.length = i;
+						break;
+					}
+				}
+			} else {
+				const tag = match[3].toLowerCase();
+				const element = new FakeElement(tag);
+				let attr;
+				attrRe.lastIndex = 0;
+				while ((attr = attrRe.exec(match[4])) !== null) {
+					element.setAttribute(attr[1], decodeEntities(attr[2] ?? attr[3] ?? attr[4] ?? ""));
+				}
+				current().appendChild(element);
+				if (RAW_TEXT_ELEMENTS.has(tag)) {
+					const end = html.toLowerCase().indexOf("</" + tag, last);
+					const text = html.slice(last, end === -1 ? html.length : end);
+					if (text) {
+						element.appendChild(new FakeText(tag === "textarea" || tag === "title" ? decodeEntities(text) : text));
+					}
+					last = end === -1 ? html.length : html.indexOf(">", end) + 1;
+					tagRe.lastIndex = last;
+				} else if (!VOID_ELEMENTS.has(tag) && !match[5]) {
+					stack.push(element);
+				}
+			}
+		}
+		if (last < html.length) {
+			current().appendChild(new FakeText(decodeEntities(html.slice(last))));
+		}
+		const nodes = root.childNodes.slice();
+		for (const node of nodes) {
+			node.parentNode = null;
+		}
+		return nodes;
+	}
+
+	class FakeDocument extends FakeNode {
+		constructor() {
+			super(9, "#document");
+			this.documentElement = new FakeElement("html");
+			this.head = new FakeElement("head");
+			this.body = new FakeElement("body");
+			this.documentElement.appendChild(this.head);
+			this.documentElement.appendChild(this.body);
+			this.appendChild(this.documentElement);
+			this.activeElement = this.body;
+			this.readyState = "complete";
+			this.cookie = "";
+		}
+		get title() {
+			const title = this.querySelector("title");
+			return title ? title.textContent : "";
+		}
+		set title(v) {
+			let title = this.querySelector("title");
+			if (!title) {
+				title = this.createElement("title");
+				this.head.appendChild(title);
+			}
+			title.textContent = v;
+		}
+		get location() { return g.location; }
+		get defaultView() { return g; }
+		createElement(tag) { return new FakeElement(String(tag)); }
+		createElementNS(ns, tag) { return new FakeElement(String(tag), ns); }
+		createTextNode(text) { return new FakeText(String(text)); }
+		createComment(text) { return new FakeComment(String(text)); }
+		createDocumentFragment() { return new FakeFragment(); }
+		createEvent() { return new g.Event(""); }
+		getElementById(id) { return descendants(this).find(function (e) { return e.id === id; }) || null; }
+		querySelectorAll(selector) { return this.documentElement.querySelectorAll(selector).concat(this.documentElement.matches(selector) ? [this.documentElement] : []); }
+		querySelector(selector) { return this.querySelectorAll(selector)[0] || null; }
+		getElementsByTagName(tag) { return FakeElement.prototype.getElementsByTagName.call(this, tag); }
+		getElementsByClassName(names) { return FakeElement.prototype.getElementsByClassName.call(this, names); }
+		execCommand() { return false; }
+		hasFocus() { return true; }
+	}
+
+	// Window
+
+	const windowTarget = new FakeNode(0, "#window");
+
+	g.window = g;
+	if (typeof g.self === "undefined") {
+		g.self = g;
+	}
+	g.addEventListener = windowTarget.addEventListener.bind(windowTarget);
+	g.removeEventListener = windowTarget.removeEventListener.bind(windowTarget);
+	g.dispatchEvent = function (event) {
+		defineEventProperty(event, "target", g);
+		defineEventProperty(event, "currentTarget", g);
+		defineEventProperty(event, "eventPhase", 2);
+		windowTarget._invoke(event, false);
+		return !event.defaultPrevented;
+	};
+
+	g.Node = FakeNode;
+	g.Text = FakeText;
+	g.Comment = FakeComment;
+	g.DocumentFragment = FakeFragment;
+	g.Element = FakeElement;
+	g.HTMLElement = FakeElement;
+	g.SVGElement = FakeElement;
+	g.Document = FakeDocument;
+	g.HTMLDocument = FakeDocument;
+	g.document = new FakeDocument();
+
+	g.DOMParser = class {
+		parseFromString(html) {
+			const doc = new FakeDocument();
+			doc.body.innerHTML = html;
+			return doc;
+		}
+	};
+
+	// Location and history
+
+	let currentURL = new URL("http://localhost/");
+
+	const location = {
+		get href() { return currentURL.href; },
+		set href(v) { location.assign(v); },
+		get origin() { return currentURL.origin; },
+		get protocol() { return currentURL.protocol; },
+		get host() { return currentURL.host; },
+		get hostname() { return currentURL.hostname; },
+		get port() { return currentURL.port; },
+		get pathname() { return currentURL.pathname; },
+		get search() { return currentURL.search; },
+		get hash() { return currentURL.hash; },
+		assign: function (url) { g.history.pushState(null, "", url); },
+		replace: function (url) { g.history.replaceState(null, "", url); },
+		reload: function () { },
+		toString: function () { return currentURL.href; },
+	};
+	g.location = location;
+
+	const entries = [{ state: null, url: currentURL.href }];
+	let index = 0;
+
+	const go = function (delta) {
+		const next = index + delta;
+		if (delta === 0 || next < 0 || next >= entries.length) {
+			return;
+		}
+		index = next;
+		currentURL = new URL(entries[index].url);
+		setTimeout(function () {
+			g.dispatchEvent(new g.PopStateEvent("popstate", { state: entries[index].state }));
+		}, 0);
+	};
+
+	g.history = {
+		get length() { return entries.length; },
+		get state() { return entries[index].state; },
+		scrollRestoration: "auto",
+		pushState: function (state, title, url) {
+			if (url !== undefined && url !== null) {
+				currentURL = new URL(String(url), currentURL);
+			}
+			entries.length = index + 1;
+			entries.push({ state: state, url: currentURL.href });
+			index++;
+		},
+		replaceState: function (state, title, url) {
+			if (url !== undefined && url !== null) {
+				currentURL = new URL(String(url), currentURL);
+			}
+			entries[index] = { state: state, url: currentURL.href };
+		},
+		back: function () { go(-1); },
+		forward: function () { go(1); },
+		go: function (delta) { go(delta || 0); },
+	};
+
+	// Other browser APIs
+
+	if (typeof g.navigator === "undefined") {
+		g.navigator = { userAgent: "crater-fakedom", language: "en-US", languages: ["en-US"], onLine: true };
+	}
+
+	const hasLocalStorage = (function () {
+		try {
+			return typeof g.localStorage !== "undefined" && g.localStorage !== null && typeof g.localStorage.getItem === "function";
+		} catch (e) {
+			return false;
+		}
+	})();
+	if (!hasLocalStorage) {
+		const makeStorage = function () {
+			const items = new Map();
+			return {
+				get length() { return items.size; },
+				key: function (i) { return Array.from(items.keys())[i] ?? null; },
+				getItem: function (k) { return items.has(String(k)) ? items.get(String(k)) : null; },
+				setItem: function (k, v) { items.set(String(k), String(v)); },
+				removeItem: function (k) { items.delete(String(k)); },
+				clear: function () { items.clear(); },
+			};
+		};
+		Object.defineProperty(g, "localStorage", { value: makeStorage(), configurable: true, writable: true });
+		Object.defineProperty(g, "sessionStorage", { value: makeStorage(), configurable: true, writable: true });
+	}
+
+	g.innerWidth = 1024;
+	g.innerHeight = 768;
+	g.devicePixelRatio = 1;
+	g.scrollX = 0;
+	g.scrollY = 0;
+	g.scrollTo = function () { };
+	g.scrollBy = function () { };
+	g.open = function () { return null; };
+	g.alert = function () { };
+	g.confirm = function () { return true; };
+	g.prompt = function () { return null; };
+	g.getComputedStyle = function (element) { return element.style; };
+	g.matchMedia = function (query) {
+		return {
+			matches: false,
+			media: query,
+			addListener: function () { },
+			removeListener: function () { },
+			addEventListener: function () { },
+			removeEventListener: function () { },
+		};
+	};
+	g.requestAnimationFrame = function (fn) { return setTimeout(function () { fn(Date.now()); }, 16); };
+	g.cancelAnimationFrame = function (id) { clearTimeout(id); };
+})(globalThis);
@@ -0,0 +1,225 @@
+package crater
+
+import (
+	"sync"
+
+	"github.com/Nigel2392/crater/craterhttp"
+	"github.com/Nigel2392/crater/decoder"
+)
+
+// ErrResponseNotOK is wrapped by the *craterhttp.HTTPError which is set
+// when an endpoint responds with a status code >= 400, use errors.Is to check for it.
+var ErrResponseNotOK = craterhttp.ErrStatusNotOK
+
+// EndpointOptions configures the loading and error states of HandleEndpointAsync.
+type EndpointOptions struct {
+	// Loading is served immediately, while the request is in flight.
+	//
+	// This can be used to render a skeleton of the page.
+	Loading PageFunc
+
+	// Error is served when the request fails, or the server responds with a status code >= 400.
+	//
+	// Page.Err will be set, Page.Response will also be set if the server responded.
+	//
+	// If the server responded with a status code >= 400, Page.Err is a *craterhttp.HTTPError
+	// which holds the response body, use its Message, Decode or JSON methods to read it.
+	//
+	// If nil, the error will be logged and passed to the OnResponseError function.
+	Error PageFunc
+}
+
+// Handle a path with a page function, without blocking the render on the request.
+//
+// The loading page is rendered immediately, the request is made after it is rendered.
+// When the request finishes, the canvas is cleared and the page function or error page is served onto it.
+//
+// The page passed to the page function will have access to page.DecodeResponse and page.Response fields.
+//
+// If the user navigates away before the request finishes, the response is discarded.
+func (a *App) HandleEndpointAsync(path string, r craterhttp.RequestFunc, h PageFunc, opts *EndpointOptions) Route {
+	if opts == nil {
+		opts = &EndpointOptions{}
+	}
+	a.LogDebugf("Adding async handler for path: %s", path)
+	return a.Handle(path, ToPageFunc(func(p *Page) {
+		a.LogInfof("Handling async endpoint: %s", path)
+		if opts.Loading != nil {
+			opts.Loading.Serve(p)
+		}
+		var afterRender = p.AfterRender
+		p.AfterRender = func(p *Page) {
+			if afterRender != nil {
+				afterRender(p)
+			}
+			go serveAsync(p, r, h, opts.Error)
+		}
+	}))
+}
+
+// Handle a path on the default application without blocking the render on the request, see App.HandleEndpointAsync.
+func HandleEndpointAsync(path string, r craterhttp.RequestFunc, h PageFunc, opts *EndpointOptions) Route {
+	checkApp()
+	return application.HandleEndpointAsync(path, r, h, opts)
+}
+
+// Make the request for an async endpoint, and swap the result onto the page's canvas.
+func serveAsync(p *Page, r craterhttp.RequestFunc, h PageFunc, errorPage PageFunc) {
+	defer func() {
+		if rec := recover(); rec == nil {
+			return
+		} else if p.Context.Err() != nil {
+			p.app.LogErrorf("Recovered from panic while serving async endpoint %s: %v", p.path, rec)
+		} else {
+			p.app.recoverPage(rec, &Navigation{To: p.path}, p)
+		}
+	}()
+
+	var err error
+	p.Response, err = doPageRequest(p, r)
+
+	// The page was replaced while the request was in flight.
+	if p.Context.Err() != nil {
+		p.app.LogDebugf("Discarding response for %s, page was replaced", p.path)
+		return
+	}
+
+	if err == nil && p.Response.StatusCode >= 400 {
+		err = craterhttp.NewHTTPError(p.Response)
+	}
+
+	p.Clear()
+	p.AfterRender = nil
+	if err != nil {
+		p.Err = err
+		if errorPage == nil {
+			p.app.reportErr(err)
+			return
+		}
+		errorPage.Serve(p)
+	} else {
+		p.app.LogDebug("Received fetch response...")
+		h.Serve(p)
+	}
+
+	if p.AfterRender != nil {
+		p.AfterRender(p)
+	}
+
+	p.app.signals.CreateOrSend(SignalPageRendered, p)
+}
+
+// Handle a path with a page function, after making multiple requests concurrently.
+//
+// The page passed to the page function will have access to the page.Responses and page.ResponseErrors fields,
+// these are keyed by the name of the request. Use page.NamedResponse to retrieve both at once.
+//
+// The page function is always served, even if some of the requests failed.
+func (a *App) HandleEndpoints(path string, requests map[string]craterhttp.RequestFunc, h PageFunc) Route {
+	a.LogDebugf("Adding handler for path: %s", path)
+	return a.Handle(path, ToPageFunc(func(p *Page) {
+		a.LogInfof("Handling endpoints: %s", path)
+		a.ShowLoader()
+
+		var (
+			wg sync.WaitGroup
+			mu sync.Mutex
+		)
+		p.Responses = make(map[string]*craterhttp.Response, len(requests))
+		p.ResponseErrors = make(map[string]error)
+		for name, r := range requests {
+			wg.Add(1)
+			go func(name string, r craterhttp.RequestFunc) {
+				defer wg.Done()
+				var resp, err = doPageRequest(p, r)
+				mu.Lock()
+				defer mu.Unlock()
+				if err != nil {
+					p.ResponseErrors[name] = err
+					return
+				}
+				p.Responses[name] = resp
+			}(name, r)
+		}
+		wg.Wait()
+		a.HideLoader()
+
+		if p.Context.Err() != nil {
+			a.LogDebugf("Discarding responses for %s, page was replaced", path)
+			return
+		}
+
+		a.LogDebugf("Received %d fetch responses...", len(p.Responses))
+		h.Serve(p)
+	}))
+}
+
+// Handle a path on the default application after making multiple requests concurrently, see App.HandleEndpoints.
+func HandleEndpoints(path string, requests map[string]craterhttp.RequestFunc, h PageFunc) Route {
+	checkApp()
+	return application.HandleEndpoints(path, requests, h)
+}
+
+// Make a request, the request is aborted when the user navigates away from the page.
+func doPageRequest(p *Page, r craterhttp.RequestFunc) (*craterhttp.Response, error) {
+	var request, err = r(p.Variables)
+	if err != nil {
+		return nil, err
+	}
+	request.SetContext(p.Context)
+	p.app.LogDebugf("Making fetch request to %s", request.URL)
+	return p.app.client().Do(request)
+}
+
+// Handle a path with a page function which receives the decoded response.
+//
+// The response body is decoded into T with the given decoder, if the decoder is nil, JSON is used.
+//
+// If the request fails, the server responds with a status code >= 400, or the body cannot be decoded,
+// the error page is served with Page.Err set. If the error page is nil,
+// the error will be logged and passed to the OnResponseError function.
+func HandleEndpointTyped[T any](path string, r craterhttp.RequestFunc, d Decoder, h func(p *Page, v T), errorPage PageFunc) Route {
+	checkApp()
+	return HandleAppEndpointTyped(application, path, r, d, h, errorPage)
+}
+
+// Handle a path of the application with a page function which receives the decoded response, see HandleEndpointTyped.
+func HandleAppEndpointTyped[T any](a *App, path string, r craterhttp.RequestFunc, d Decoder, h func(p *Page, v T), errorPage PageFunc) Route {
+	if d == nil {
+		d = decoder.JSONDecoder
+	}
+	a.LogDebugf("Adding typed handler for path: %s", path)
+	return a.Handle(path, ToPageFunc(func(p *Page) {
+		var (
+			value T
+			err   error
+		)
+		a.LogInfof("Handling typed endpoint: %s", path)
+		a.ShowLoader()
+		p.Response, err = doPageRequest(p, r)
+		a.HideLoader()
+
+		if p.Context.Err() != nil {
+			a.LogDebugf("Discarding response for %s, page was replaced", path)
+			return
+		}
+
+		if err == nil && p.Response.StatusCode >= 400 {
+			err = craterhttp.NewHTTPError(p.Response)
+		}
+		if err == nil {
+			err = p.DecodeResponse(d, &value)
+		}
+		if err != nil {
+			p.Err = err
+			if errorPage == nil {
+				a.reportErr(err)
+				return
+			}
+			errorPage.Serve(p)
+			return
+		}
+
+		h(p, value)
+	}))
+}
@@ -0,0 +1,299 @@
+package crater
+
+import (
+	"io"
+	"net/url"
+	"strings"
+	"sync"
+	"syscall/js"
+	"time"
+
+	"github.com/Nigel2392/crater/decoder"
+	"github.com/Nigel2392/jsext/v2"
+)
+
+// ServerEvent is an event received from an EventSource.
+type ServerEvent struct {
+	// The ID of the event, if the server sent one.
+	ID string
+
+	// The type of the event, "message" if the server did not specify one.
+	Type string
+
+	// The data of the event.
+	Data string
+}
+
+// JSON decodes the data of the event into dst.
+func (e ServerEvent) JSON(dst any) error {
+	return decoder.JSONDecoder.DecodeResponse(io.NopCloser(strings.NewReader(e.Data)), dst)
+}
+
+// EventSourceOpts configures an EventSource.
+type EventSourceOpts struct {
+	// Send cookies with cross-origin requests.
+	WithCredentials bool
+
+	// The delay before reconnecting after the browser closed the connection.
+	//
+	// This doubles after each failed attempt, up to MaxReconnectDelay.
+	// If zero, a delay of one second is used.
+	ReconnectDelay time.Duration
+
+	// The maximum delay between reconnect attempts, if zero, 30 seconds is used.
+	MaxReconnectDelay time.Duration
+
+	// Do not reconnect after the browser closed the connection.
+	NoReconnect bool
+
+	OnOpen    func(*EventSource)
+	OnMessage func(*EventSource, ServerEvent)
+	OnError   func(*EventSource, jsext.Event)
+}
+
+// EventSource is a connection to a server which pushes events with Server-Sent Events.
+//
+// The browser reconnects on network errors by itself, sending the Last-Event-ID header.
+// When the browser gives up, the EventSource reconnects with exponential backoff.
+// Browsers do not allow setting headers on an EventSource, so the last event ID is then
+// sent as the "lastEventId" query parameter.
+type EventSource struct {
+	url  string
+	opts EventSourceOpts
+	app  *App
+
+	mu          sync.Mutex
+	value       js.Value
+	funcs       []js.Func
+	dispatch    js.Func
+	handlers    map[string][]func(*EventSource, ServerEvent)
+	lastEventID string
+	attempts    int
+	closed      bool
+}
+
+// Create a new EventSource, and connect to the URL.
+//
+// SignalEventSourceConnected is sent through the hooks of the default application.
+func NewEventSource(url string, opts *EventSourceOpts) *EventSource {
+	return newEventSource(nil, url, opts)
+}
+
+// Create a new EventSource which sends its signals through the hooks of the application.
+func newEventSource(a *App, url string, opts *EventSourceOpts) *EventSource {
+	var es = &EventSource{
+		url:      url,
+		app:      a,
+		handlers: make(map[string][]func(*EventSource, ServerEvent)),
+	}
+	if opts != nil {
+		es.opts = *opts
+	}
+	if es.opts.OnMessage != nil {
+		es.handlers["message"] = append(es.handlers["message"], es.opts.OnMessage)
+	}
+	es.connect()
+	return es
+}
+
+// On adds a handler for events of the given type.
+func (es *EventSource) On(event string, fn func(*EventSource, ServerEvent)) {
+	es.mu.Lock()
+	defer es.mu.Unlock()
+	var _, listening = es.handlers[event]
+	es.handlers[event] = append(es.handlers[event], fn)
+	if !listening && !es.value.IsUndefined() {
+		es.value.Call("addEventListener", event, es.dispatch)
+	}
+}
+
+// OnEvent adds a handler for events of the given type, the data is decoded from JSON into T.
+//
+// Events which cannot be decoded are logged, and not passed to the handler.
+func OnEvent[T any](es *EventSource, event string, fn func(es *EventSource, v T)) {
+	es.On(event, func(es *EventSource, e ServerEvent) {
+		var v T
+		if err := e.JSON(&v); err != nil {
+			if a := appOr(es.app); a != nil {
+				a.LogErrorf("Error decoding %s event: %s", event, err)
+			}
+			return
+		}
+		fn(es, v)
+	})
+}
+
+// URL returns the URL of the event source.
+func (es *EventSource) URL() string {
+	return es.url
+}
+
+// LastEventID returns the ID of the last event received.
+func (es *EventSource) LastEventID() string {
+	es.mu.Lock()
+	defer es.mu.Unlock()
+	return es.lastEventID
+}
+
+// IsOpen reports whether the connection is open.
+func (es *EventSource) IsOpen() bool {
+	es.mu.Lock()
+	defer es.mu.Unlock()
+	return !es.value.IsUndefined() && es.value.Get("readyState").Int() == 1
+}
+
+// Close the connection, the event source will not reconnect.
+func (es *EventSource) Close() {
+	es.mu.Lock()
+	defer es.mu.Unlock()
+	es.closed = true
+	es.release()
+}
+
+// Close the javascript EventSource, and release its functions.
+func (es *EventSource) release() {
+	if !es.value.IsUndefined() {
+		es.value.Call("close")
+		es.value = js.Undefined()
+	}
+	for _, fn := range es.funcs {
+		fn.Release()
+	}
+	es.funcs = nil
+}
+
+// Open the javascript EventSource.
+func (es *EventSource) connect() {
+	es.mu.Lock()
+	defer es.mu.Unlock()
+	if es.closed {
+		return
+	}
+	es.release()
+
+	var u = es.url
+	if es.lastEventID != "" {
+		if parsed, err := url.Parse(u); err == nil {
+			var query = parsed.Query()
+			query.Set("lastEventId", es.lastEventID)
+			parsed.RawQuery = query.Encode()
+			u = parsed.String()
+		}
+	}
+
+	var init = js.Global().Get("Object").New()
+	init.Set("withCredentials", es.opts.WithCredentials)
+	es.value = js.Global().Get("EventSource").New(u, init)
+
+	var on = func(event string, f func(this js.Value, args []js.Value) any) js.Func {
+		var fn = js.FuncOf(f)
+		es.funcs = append(es.funcs, fn)
+		es.value.Call("addEventListener", event, fn)
+		return fn
+	}
+
+	on("open", func(this js.Value, args []js.Value) any {
+		es.mu.Lock()
+		es.attempts = 0
+		es.mu.Unlock()
+		if es.opts.OnOpen != nil {
+			es.opts.OnOpen(es)
+		}
+		if a := appOr(es.app); a != nil {
+			a.signals.CreateOrSend(SignalEventSourceConnected, es)
+		}
+		return nil
+	})
+
+	on("error", func(this js.Value, args []js.Value) any {
+		if es.opts.OnError != nil && len(args) > 0 {
+			es.opts.OnError(es, jsext.Event(args[0]))
+		}
+		// The browser will reconnect by itself, unless the connection is closed.
+		if this.Get("readyState").Int() == 2 && !es.opts.NoReconnect {
+			go es.reconnect()
+		}
+		return nil
+	})
+
+	es.dispatch = js.FuncOf(func(this js.Value, args []js.Value) any {
+		if len(args) < 1 {
+			return nil
+		}
+		var event = ServerEvent{
+			ID:   args[0].Get("lastEventId").String(),
+			Type: args[0].Get("type").String(),
+			Data: args[0].Get("data").String(),
+		}
+		es.mu.Lock()
+		if event.ID != "" {
+			es.lastEventID = event.ID
+		}
+		var handlers = es.handlers[event.Type]
+		es.mu.Unlock()
+		for _, handler := range handlers {
+			handler(es, event)
+		}
+		return nil
+	})
+	es.funcs = append(es.funcs, es.dispatch)
+	for event := range es.handlers {
+		es.value.Call("addEventListener", event, es.dispatch)
+	}
+}
+
+// Reconnect after the backoff delay.
+func (es *EventSource) reconnect() {
+	es.mu.Lock()
+	var delay = es.opts.ReconnectDelay
+	if delay <= 0 {
+		delay = time.Second
+	}
+	var maxDelay = es.opts.MaxReconnectDelay
+	if maxDelay <= 0 {
+		maxDelay = 30 * time.Second
+	}
+	for i := 0; i < es.attempts && delay < maxDelay; i++ {
+		delay *= 2
+	}
+	if delay > maxDelay {
+		delay = maxDelay
+	}
+	es.attempts++
+	es.mu.Unlock()
+
+	time.Sleep(delay)
+	es.connect()
+}
+
+// Open an event source for the application.
+//
+// If the application's event source is already connected to the URL, it is returned as is,
+// otherwise the previous event source is closed.
+func (a *App) OpenEventSource(url string, options *EventSourceOpts) *EventSource {
+	if a.EventSource == nil || a.EventSource.url != url {
+		if a.EventSource != nil {
+			a.EventSource.Close()
+		}
+		a.EventSource = newEventSource(a, url, options)
+	}
+
+	return a.EventSource
+}
+
+// Open an event source for the default application, see App.OpenEventSource.
+func OpenEventSource(url string, options *EventSourceOpts) *EventSource {
+	checkApp()
+	return application.OpenEventSource(url, options)
+}
+
+// ServerEvents returns the application's event source.
+func (a *App) ServerEvents() *EventSource {
+	return a.EventSource
+}
+
+// ServerEvents returns the default application's event source.
+func ServerEvents() *EventSource {
+	checkApp()
+	return application.ServerEvents()
+}
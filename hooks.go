@@ -10,19 +10,60 @@ const (
 	// The value sent is the error.
 	SignalExit = "crater.Exit"
 
+	// SignalShutdown is sent when the application is shut down, before the teardown hooks run.
+	//
+	// The value sent is the *App.
+	SignalShutdown = "crater.Shutdown"
+
 	// SignalPageChange is sent when a page is changed.
+	//
+	// The value sent is the *Navigation.
 	SignalPageChange = "crater.PageChange"
 
+	// SignalNavigationCancelled is sent when a navigation guard cancels a page change.
+	//
+	// The value sent is the *Navigation.
+	SignalNavigationCancelled = "crater.NavigationCancelled"
+
 	// SignalPageRendered is sent when a page is rendered.
 	//
+	// For HandleEndpointAsync, this is sent again when the response has been rendered.
+	//
 	// The value sent is the page.
 	SignalPageRendered = "crater.PageRendered"
 
+	// SignalPageDestroyed is sent when the user navigates away from a page.
+	//
+	// The value sent is the page.
+	SignalPageDestroyed = "crater.PageDestroyed"
+
+	// SignalPagePanic is sent when a page panics, before the error page is served.
+	//
+	// SignalPageRendered is sent after the error page is rendered.
+	//
+	// The value sent is the *PanicError.
+	SignalPagePanic = "crater.PagePanic"
+
 	// SignalSockConnected is sent when a websocket is connected.
 	//
 	// The value sent is the websocket.
 	SignalSockConnected = "crater.SockConnected"
 
+	// SignalSockClosed is sent when a named websocket is closed.
+	//
+	// The value sent is the websocket.
+	SignalSockClosed = "crater.SockClosed"
+
+	// SignalSockStateChange is sent when the state of a managed websocket changes.
+	//
+	// The value sent is the *ManagedSocket, use ManagedSocket.State to get the new state.
+	SignalSockStateChange = "crater.SockStateChange"
+
+	// SignalEventSourceConnected is sent when an event source is connected, or reconnected.
+	//
+	// The value sent is the *EventSource.
+	SignalEventSourceConnected = "crater.EventSourceConnected"
+
 	// SignalClientResponse is sent when the client receives a response.
 	//
 	// The value sent is the client.
@@ -25,6 +25,18 @@ type (
 	Messenger messenger.Messenger
 )
 
+// Remover is implemented by a messenger or loader which removes its elements
+// from the document when the application is shut down.
+type Remover interface {
+	Remove()
+}
+
+// TaskClearer is implemented by a tasker which can dequeue all of its tasks
+// when the application is shut down.
+type TaskClearer interface {
+	DequeueAll() error
+}
+
 type Marshaller interface {
 	MarshalJS() js.Value
 }
@@ -47,6 +59,11 @@ type Initter interface {
 	Init()
 }
 
+// Destroyer is called when the user navigates away from the page, before the next page is rendered.
+type Destroyer interface {
+	OnLeave(p *Page)
+}
+
 type Templater interface {
 	Templates() map[string]func(args ...interface{}) Marshaller
 }
@@ -55,6 +72,11 @@ type SockConfigurator interface {
 	SockOptions() (url string, opts SockOpts)
 }
 
+// EventSourceConfigurator opens an event source for the page, this is available as Page.EventSource.
+type EventSourceConfigurator interface {
+	EventSourceOptions() (url string, opts EventSourceOpts)
+}
+
 type FullPage interface {
 	PageFunc
 	Preloader
@@ -63,6 +85,22 @@ type FullPage interface {
 	SockConfigurator
 }
 
+// Middleware wraps a page function.
+//
+// A middleware can run logic before and after calling the next page function,
+// or short-circuit the chain by not calling it at all. Use Page.Redirect to send the user elsewhere.
+type Middleware func(next PageFunc) PageFunc
+
 type Route interface {
 	Handle(path string, h PageFunc) Route
+
+	// Use adds middleware to the route.
+	//
+	// The middleware will also be applied to all child routes.
+	Use(m ...Middleware) Route
+
+	// BeforeEnter adds navigation guards to the route.
+	//
+	// The guards will also be applied to all child routes.
+	BeforeEnter(g ...NavigationGuard) Route
 }
@@ -0,0 +1,415 @@
+package crater
+
+import (
+	"sync"
+	"syscall/js"
+	"time"
+
+	"github.com/Nigel2392/jsext/v2"
+	"github.com/Nigel2392/jsext/v2/encoding"
+	"github.com/Nigel2392/jsext/v2/errs"
+	"github.com/Nigel2392/jsext/v2/websocket"
+)
+
+var (
+	ErrSendQueueFull = errs.Error("websocket send queue is full")
+	ErrSockClosed    = errs.Error("websocket is closed")
+)
+
+// The state of a managed websocket.
+type SockState int
+
+const (
+	// The socket is connecting for the first time.
+	SockConnecting SockState = iota
+
+	// The socket is open, messages are sent immediately.
+	SockOpen
+
+	// The connection was lost, the socket is waiting to reconnect.
+	SockReconnecting
+
+	// The socket was closed, and will not reconnect.
+	SockClosed
+)
+
+func (s SockState) String() string {
+	switch s {
+	case SockConnecting:
+		return "connecting"
+	case SockOpen:
+		return "open"
+	case SockReconnecting:
+		return "reconnecting"
+	case SockClosed:
+		return "closed"
+	}
+	return "unknown"
+}
+
+// ManagedSockOpts configures a ManagedSocket.
+type ManagedSockOpts struct {
+	// The options which are applied to each underlying websocket.
+	SockOpts
+
+	// The delay before the first reconnect attempt, this doubles after each failed attempt.
+	//
+	// If zero, a delay of one second is used.
+	ReconnectDelay time.Duration
+
+	// The maximum delay between reconnect attempts, if zero, 30 seconds is used.
+	MaxReconnectDelay time.Duration
+
+	// The maximum number of consecutive reconnect attempts, if zero, there is no limit.
+	MaxReconnectAttempts int
+
+	// The maximum number of messages buffered while disconnected, if zero, 100 is used.
+	QueueSize int
+
+	// The interval at which a ping is sent, if zero, heartbeats are disabled.
+	HeartbeatInterval time.Duration
+
+	// How long to wait for a pong after a ping before the connection is considered dead.
+	//
+	// If zero, the heartbeat interval is used.
+	HeartbeatTimeout time.Duration
+
+	// The message sent as a ping, if nil, "ping" is used.
+	PingMessage []byte
+
+	// Reports whether a message is a pong, pongs are not passed to OnMessage.
+	//
+	// If nil, a message is a pong if its data is "pong".
+	IsPong func(event websocket.MessageEvent) bool
+
+	// Called when the state of the socket changes.
+	OnStateChange func(m *ManagedSocket, state SockState)
+}
+
+// ManagedSocket is a websocket which reconnects with exponential backoff when the connection is lost.
+//
+// Messages sent while disconnected are buffered, and flushed when the socket reconnects.
+// State changes are sent with SignalSockStateChange.
+type ManagedSocket struct {
+	url  string
+	opts ManagedSockOpts
+	app  *App
+
+	mu       sync.Mutex
+	sock     *websocket.WebSocket
+	state    SockState
+	queue    [][]byte
+	attempts int
+	lastPong time.Time
+	stop     chan struct{}
+
+	openFuncs    []func(*ManagedSocket)
+	messageFuncs []func(*ManagedSocket, websocket.MessageEvent)
+}
+
+// Create a new managed websocket, and connect to the URL.
+//
+// State changes are sent through the hooks of the default application.
+func NewManagedSocket(url string, opts *ManagedSockOpts) *ManagedSocket {
+	return newManagedSocket(nil, url, opts)
+}
+
+// Create a new managed websocket which sends its signals through the hooks of the application.
+func newManagedSocket(a *App, url string, opts *ManagedSockOpts) *ManagedSocket {
+	var m = &ManagedSocket{
+		url:   url,
+		app:   a,
+		state: SockConnecting,
+	}
+	if opts != nil {
+		m.opts = *opts
+	}
+	if m.opts.QueueSize <= 0 {
+		m.opts.QueueSize = 100
+	}
+	if m.opts.PingMessage == nil {
+		m.opts.PingMessage = []byte("ping")
+	}
+	if m.opts.HeartbeatTimeout <= 0 {
+		m.opts.HeartbeatTimeout = m.opts.HeartbeatInterval
+	}
+	m.connect()
+	return m
+}
+
+// URL returns the URL of the socket.
+func (m *ManagedSocket) URL() string {
+	return m.url
+}
+
+// State returns the current state of the socket.
+func (m *ManagedSocket) State() SockState {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.state
+}
+
+// Socket returns the current underlying websocket.
+//
+// This changes each time the socket reconnects.
+func (m *ManagedSocket) Socket() *websocket.WebSocket {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.sock
+}
+
+// OnOpen adds a function which is called each time the socket is (re)connected.
+func (m *ManagedSocket) OnOpen(fn func(m *ManagedSocket)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.openFuncs = append(m.openFuncs, fn)
+}
+
+// OnMessage adds a function which is called for each message received, pongs excluded.
+func (m *ManagedSocket) OnMessage(fn func(m *ManagedSocket, e websocket.MessageEvent)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.messageFuncs = append(m.messageFuncs, fn)
+}
+
+// Send a message, if the socket is not open the message is buffered until it reconnects.
+func (m *ManagedSocket) Send(data []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	switch m.state {
+	case SockClosed:
+		return ErrSockClosed
+	case SockOpen:
+		if err := m.sock.SendBytes(data); err == nil {
+			return nil
+		}
+	}
+	if len(m.queue) >= m.opts.QueueSize {
+		return ErrSendQueueFull
+	}
+	m.queue = append(m.queue, data)
+	return nil
+}
+
+// SendJSON encodes v as JSON, and sends it.
+func (m *ManagedSocket) SendJSON(v any) error {
+	var data, err = encoding.EncodeJSON[[]byte](v)
+	if err != nil {
+		return err
+	}
+	return m.Send(data)
+}
+
+// Close the socket, it will not reconnect.
+//
+// Buffered messages are discarded.
+func (m *ManagedSocket) Close() {
+	m.mu.Lock()
+	var sock = m.sock
+	m.queue = nil
+	m.stopHeartbeat()
+	m.mu.Unlock()
+	m.setState(SockClosed)
+	if sock != nil {
+		sock.Close(1000)
+	}
+}
+
+// Change the state of the socket, and notify the listeners.
+func (m *ManagedSocket) setState(state SockState) {
+	m.mu.Lock()
+	if m.state == state || m.state == SockClosed {
+		m.mu.Unlock()
+		return
+	}
+	m.state = state
+	m.mu.Unlock()
+
+	if m.opts.OnStateChange != nil {
+		m.opts.OnStateChange(m, state)
+	}
+	if a := appOr(m.app); a != nil {
+		a.signals.CreateOrSend(SignalSockStateChange, m)
+	}
+}
+
+// Open a new underlying websocket.
+func (m *ManagedSocket) connect() {
+	var sock = m.opts.SockOpts.OpenSock(m.url)
+
+	sock.OnOpen(func(w *websocket.WebSocket, e websocket.MessageEvent) {
+		m.mu.Lock()
+		m.attempts = 0
+		m.lastPong = time.Now()
+		var openFuncs = m.openFuncs
+		m.mu.Unlock()
+		m.setState(SockOpen)
+		m.flush()
+		m.startHeartbeat(w)
+		if m.opts.OnOpen != nil {
+			m.opts.OnOpen(w, e)
+		}
+		for _, fn := range openFuncs {
+			fn(m)
+		}
+	})
+	sock.OnMessage(func(w *websocket.WebSocket, e websocket.MessageEvent) {
+		if m.isPong(e) {
+			m.mu.Lock()
+			m.lastPong = time.Now()
+			m.mu.Unlock()
+			return
+		}
+		if m.opts.OnMessage != nil {
+			m.opts.OnMessage(w, e)
+		}
+		m.mu.Lock()
+		var messageFuncs = m.messageFuncs
+		m.mu.Unlock()
+		for _, fn := range messageFuncs {
+			fn(m, e)
+		}
+	})
+	sock.OnClose(func(w *websocket.WebSocket, e jsext.Event) {
+		m.mu.Lock()
+		m.stopHeartbeat()
+		var closed = m.state == SockClosed || m.sock != w
+		m.mu.Unlock()
+		if m.opts.OnClose != nil {
+			m.opts.OnClose(w, e)
+		}
+		if !closed {
+			m.setState(SockReconnecting)
+			go m.reconnect()
+		}
+	})
+	if m.opts.OnError != nil {
+		sock.OnError(m.opts.OnError)
+	}
+
+	m.mu.Lock()
+	m.sock = sock
+	m.mu.Unlock()
+}
+
+// Reconnect after the backoff delay.
+func (m *ManagedSocket) reconnect() {
+	m.mu.Lock()
+	if m.opts.MaxReconnectAttempts > 0 && m.attempts >= m.opts.MaxReconnectAttempts {
+		m.mu.Unlock()
+		if a := appOr(m.app); a != nil {
+			a.LogErrorf("Giving up reconnecting to %s after %d attempts", m.url, m.attempts)
+		}
+		m.Close()
+		return
+	}
+	var delay = m.opts.ReconnectDelay
+	if delay <= 0 {
+		delay = time.Second
+	}
+	var maxDelay = m.opts.MaxReconnectDelay
+	if maxDelay <= 0 {
+		maxDelay = 30 * time.Second
+	}
+	for i := 0; i < m.attempts && delay < maxDelay; i++ {
+		delay *= 2
+	}
+	if delay > maxDelay {
+		delay = maxDelay
+	}
+	m.attempts++
+	m.mu.Unlock()
+
+	time.Sleep(delay)
+	if m.State() == SockClosed {
+		return
+	}
+	m.connect()
+}
+
+// Send all buffered messages.
+func (m *ManagedSocket) flush() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for len(m.queue) > 0 {
+		if err := m.sock.SendBytes(m.queue[0]); err != nil {
+			return
+		}
+		m.queue = m.queue[1:]
+	}
+}
+
+func (m *ManagedSocket) isPong(e websocket.MessageEvent) bool {
+	if m.opts.HeartbeatInterval <= 0 {
+		return false
+	}
+	if m.opts.IsPong != nil {
+		return m.opts.IsPong(e)
+	}
+	var data = e.Data()
+	return data.Type() == js.TypeString && data.String() == "pong"
+}
+
+// Send pings at the heartbeat interval, closing the socket when no pong is received in time.
+func (m *ManagedSocket) startHeartbeat(w *websocket.WebSocket) {
+	if m.opts.HeartbeatInterval <= 0 {
+		return
+	}
+	m.mu.Lock()
+	m.stopHeartbeat()
+	var stop = make(chan struct{})
+	m.stop = stop
+	m.mu.Unlock()
+
+	go func() {
+		var ticker = time.NewTicker(m.opts.HeartbeatInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+			}
+			m.mu.Lock()
+			var dead = time.Since(m.lastPong) > m.opts.HeartbeatInterval+m.opts.HeartbeatTimeout
+			m.mu.Unlock()
+			if dead {
+				if a := appOr(m.app); a != nil {
+					a.LogDebugf("No pong received from %s, reconnecting", m.url)
+				}
+				w.Close(4000, "heartbeat timeout")
+				return
+			}
+			w.SendBytes(m.opts.PingMessage)
+		}
+	}()
+}
+
+// Stop the heartbeat, the mutex must be held.
+func (m *ManagedSocket) stopHeartbeat() {
+	if m.stop != nil {
+		close(m.stop)
+		m.stop = nil
+	}
+}
+
+// Open a managed websocket for the application.
+//
+// If the application's managed websocket is already connected to the URL, it is returned as is,
+// otherwise the previous socket is closed.
+func (a *App) OpenManagedSock(url string, options *ManagedSockOpts) *ManagedSocket {
+	if a.ManagedSocket == nil || a.ManagedSocket.url != url || a.ManagedSocket.State() == SockClosed {
+		if a.ManagedSocket != nil {
+			a.ManagedSocket.Close()
+		}
+		a.ManagedSocket = newManagedSocket(a, url, options)
+	}
+
+	return a.ManagedSocket
+}
+
+// Open a managed websocket for the default application, see App.OpenManagedSock.
+func OpenManagedSock(url string, options *ManagedSockOpts) *ManagedSocket {
+	checkApp()
+	return application.OpenManagedSock(url, options)
+}
@@ -151,6 +151,13 @@ func (m *SimpleMessenger) Success(duration time.Duration, args ...any) {
 	m.displayMessage(duration, "success", m.Colors.ForeGround.Success, m.Colors.BackGround.Success, args...)
 }
 
+// Remove the messenger's container, and the messages in it, from the document.
+//
+// The messenger must not be used after it is removed.
+func (m *SimpleMessenger) Remove() {
+	m.Element.Call("remove")
+}
+
 type OnClickFunc func()
 
 func (f OnClickFunc) OnClick() {
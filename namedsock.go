@@ -0,0 +1,100 @@
+package crater
+
+import (
+	"github.com/Nigel2392/jsext/v2"
+	"github.com/Nigel2392/jsext/v2/websocket"
+)
+
+type namedSock struct {
+	url  string
+	sock *websocket.WebSocket
+}
+
+// SockSignal returns the name of a signal for the named socket.
+//
+// For example, SockSignal(SignalSockConnected, "chat") is sent when the "chat" socket connects.
+func SockSignal(signal, name string) string {
+	return signal + ":" + name
+}
+
+// Open a named websocket for the application.
+//
+// If the socket with the name is already connecting or connected to the URL, it is returned as is,
+// otherwise the previous socket with the name is closed.
+//
+// SignalSockConnected and SignalSockClosed are sent when the socket connects and closes,
+// both as is and as returned by SockSignal for the name.
+//
+// Named sockets are not closed when the page changes, use CloseNamedSock to close them.
+func (a *App) OpenNamedSock(name, url string, options *SockOpts) *websocket.WebSocket {
+	a.socketsMut.Lock()
+	defer a.socketsMut.Unlock()
+
+	if s, ok := a.sockets[name]; ok {
+		var state = s.sock.ReadyState()
+		if s.url == url && (state == websocket.SockConnecting || state == websocket.SockOpen) {
+			return s.sock
+		}
+		s.sock.Close()
+	}
+
+	var sock = options.OpenSock(url)
+	sock.OnOpen(func(w *websocket.WebSocket, e websocket.MessageEvent) {
+		a.signals.CreateOrSend(SignalSockConnected, w)
+		a.signals.CreateOrSend(SockSignal(SignalSockConnected, name), w)
+	})
+	sock.OnClose(func(w *websocket.WebSocket, e jsext.Event) {
+		a.signals.CreateOrSend(SignalSockClosed, w)
+		a.signals.CreateOrSend(SockSignal(SignalSockClosed, name), w)
+	})
+	if options != nil {
+		options.Apply(sock)
+	}
+
+	a.sockets[name] = &namedSock{
+		url:  url,
+		sock: sock,
+	}
+	return sock
+}
+
+// Open a named websocket for the default application, see App.OpenNamedSock.
+func OpenNamedSock(name, url string, options *SockOpts) *websocket.WebSocket {
+	checkApp()
+	return application.OpenNamedSock(name, url, options)
+}
+
+// SocketByName returns the named websocket, or nil if no socket was opened with the name.
+func (a *App) SocketByName(name string) *websocket.WebSocket {
+	a.socketsMut.Lock()
+	defer a.socketsMut.Unlock()
+
+	if s, ok := a.sockets[name]; ok {
+		return s.sock
+	}
+	return nil
+}
+
+// SocketByName returns the named websocket of the default application.
+func SocketByName(name string) *websocket.WebSocket {
+	checkApp()
+	return application.SocketByName(name)
+}
+
+// Close the named websocket, and remove it from the application.
+func (a *App) CloseNamedSock(name string) {
+	a.socketsMut.Lock()
+	var s, ok = a.sockets[name]
+	delete(a.sockets, name)
+	a.socketsMut.Unlock()
+
+	if ok {
+		s.sock.Close()
+	}
+}
+
+// Close the named websocket, and remove it from the default application.
+func CloseNamedSock(name string) {
+	checkApp()
+	application.CloseNamedSock(name)
+}
@@ -0,0 +1,146 @@
+package crater
+
+import (
+	"strings"
+
+	"github.com/Nigel2392/jsext/v2/errs"
+	"github.com/Nigel2392/jsext/v2/history"
+	"github.com/Nigel2392/mux"
+)
+
+// ErrNavigationCancelled can be returned by a navigation guard to cancel the navigation.
+//
+// Any other error will also cancel the navigation, but will be logged.
+var ErrNavigationCancelled = errs.Error("navigation cancelled")
+
+// A navigation guard is called before the page is changed.
+//
+// Returning an error will cancel the navigation, and leave the previous page intact.
+//
+// Calling Navigation.Redirect will cancel the navigation, and redirect the user to the given path.
+type NavigationGuard func(n *Navigation) error
+
+// Navigation describes a page change.
+//
+// It is passed to navigation guards, and sent with SignalPageChange.
+type Navigation struct {
+	// The unique ID of the navigation.
+	//
+	// This is also available on the page's context with NavigationIDFromContext.
+	ID uint64
+
+	// The path and query of the page the user is navigating away from.
+	//
+	// This is empty for the first page.
+	From string
+
+	// The variables of the page the user is navigating away from.
+	FromVariables mux.Variables
+
+	// The path the user is navigating to.
+	To string
+
+	// The variables of the page the user is navigating to.
+	ToVariables mux.Variables
+
+	// The path to redirect to, if any.
+	redirect string
+
+	// How the navigation was started by the router.
+	request navigationRequest
+}
+
+// Redirect cancels the navigation, and sends the user to the given path instead.
+func (n *Navigation) Redirect(path string) {
+	n.redirect = path
+}
+
+// BeforeLeave adds a navigation guard which will run before any page is left.
+func (a *App) BeforeLeave(g ...NavigationGuard) {
+	a.leaveGuards = append(a.leaveGuards, g...)
+}
+
+// BeforeLeave adds a navigation guard to the default application, see App.BeforeLeave.
+func BeforeLeave(g ...NavigationGuard) {
+	checkApp()
+	application.BeforeLeave(g...)
+}
+
+// BeforeEnter adds a navigation guard which will run before any page is entered.
+//
+// Global guards are always run before route-level guards.
+func (a *App) BeforeEnter(g ...NavigationGuard) {
+	a.enterGuards = append(a.enterGuards, g...)
+}
+
+// BeforeEnter adds a navigation guard to the default application, see App.BeforeEnter.
+func BeforeEnter(g ...NavigationGuard) {
+	checkApp()
+	application.BeforeEnter(g...)
+}
+
+// Returns the path which mux.Mux has stored in the variables.
+func variablesPath(v mux.Variables) string {
+	var paths = v.GetAll("path")
+	if len(paths) == 0 {
+		return ""
+	}
+	return paths[len(paths)-1]
+}
+
+// Run the leave guards of the current page, followed by the enter guards of the route.
+//
+// If the navigation is cancelled, the URL will be restored to the previous page.
+func (a *App) runGuards(nav *Navigation, rt *route) bool {
+	var guards = make([]NavigationGuard, 0, len(a.leaveGuards)+len(a.enterGuards))
+	guards = append(guards, a.leaveGuards...)
+	if a.page != nil {
+		guards = append(guards, a.page.leaveGuards...)
+	}
+	guards = append(guards, a.enterGuards...)
+	guards = append(guards, rt.guards()...)
+
+	for _, guard := range guards {
+		var err = guard(nav)
+		if err == nil && nav.redirect == "" {
+			continue
+		}
+
+		if err != nil && err != ErrNavigationCancelled {
+			a.LogErrorf("Navigation from %s to %s cancelled: %s", nav.From, nav.To, err)
+		} else {
+			a.LogDebugf("Navigation from %s to %s cancelled", nav.From, nav.To)
+		}
+
+		// The next click on the link must be handled again.
+		a.currentPath, _, _ = strings.Cut(nav.From, "?")
+
+		// The history was already changed, traverse it back so the previous page is the current entry again.
+		// The redirect is handled once the history has been traversed.
+		var delta int
+		switch {
+		case nav.From == "" || a.noHistory():
+		case nav.request.mode == historyPush:
+			delta = -1
+		case nav.request.delta != 0:
+			delta = -nav.request.delta
+		default:
+			history.ReplaceState(historyState(a.historyIndex), "", nav.From)
+		}
+		if delta != 0 {
+			a.skipPop = true
+			a.popRedirect = nav.redirect
+			a.signals.CreateOrSend(SignalNavigationCancelled, nav)
+			history.Go(delta)
+			return false
+		}
+
+		a.signals.CreateOrSend(SignalNavigationCancelled, nav)
+
+		if nav.redirect != "" {
+			a.HandlePath(nav.redirect)
+		}
+		return false
+	}
+	return true
+}
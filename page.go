@@ -4,6 +4,7 @@ import (
 	"context"
 
 	"github.com/Nigel2392/crater/craterhttp"
+	"github.com/Nigel2392/crater/tasker"
 	"github.com/Nigel2392/jsext/v2/dom"
 	"github.com/Nigel2392/jsext/v2/jse"
 	"github.com/Nigel2392/jsext/v2/state"
@@ -32,12 +33,28 @@ type Page struct {
 	// The response received from the server
 	*craterhttp.Response `jsc:"-"`
 
+	// The responses received for HandleEndpoints, by request name.
+	Responses map[string]*craterhttp.Response `jsc:"-"`
+
+	// The errors which occurred for HandleEndpoints, by request name.
+	//
+	// Requests which succeeded will not have an entry.
+	ResponseErrors map[string]error `jsc:"-"`
+
 	// The variables received from the server
 	Variables mux.Variables `jsc:"variables"`
 
+	// The error which occurred while loading the page, if any.
+	//
+	// This is set when an error page is served.
+	Err error `jsc:"-"`
+
 	// The context of the page
 	//
-	// This will be reset for each page render.
+	// This will be reset for each page render, and cancelled when the page is replaced.
+	//
+	// The path, variables and navigation ID are available with
+	// PathFromContext, VariablesFromContext and NavigationIDFromContext.
 	Context context.Context `jsc:"-"`
 
 	// A function which can be arbitrarily set, and will be called after the page is rendered.
@@ -50,6 +67,117 @@ type Page struct {
 
 	// Sock is a websocket connection to the server for the current page.
 	Sock *websocket.WebSocket `jsc:"-"`
+
+	// EventSource is a Server-Sent Events connection to the server for the current page.
+	EventSource *EventSource `jsc:"-"`
+
+	// The application which rendered the page.
+	app *App
+
+	// The path to redirect to after the page function returns.
+	redirect string
+
+	// The path of the page.
+	path string
+
+	// The path and query of the page, as navigated to.
+	uri string
+
+	// Guards which will run before the user leaves this page.
+	leaveGuards []NavigationGuard
+
+	// Functions which will run when the user leaves this page.
+	onDestroy []func(p *Page)
+
+	// Names of the tasks which will be dequeued when the user leaves this page.
+	tasks []string
+
+	// The page function which served this page.
+	handler PageFunc
+
+	// Cancels the page's context.
+	cancel context.CancelFunc
+
+	// Whether the page was rendered, pages which were replaced or redirected while loading are not.
+	rendered bool
+}
+
+// OnDestroy adds functions which will run when the user navigates away from this page.
+//
+// The functions are called in the order they were added, before the next page is rendered.
+//
+// This is useful for releasing js.Func event listeners, closing connections, etc.
+func (p *Page) OnDestroy(f ...func(p *Page)) {
+	p.onDestroy = append(p.onDestroy, f...)
+}
+
+// Enqueue a task periodically by name, the task is scoped to this page.
+//
+// The task will be dequeued when the user navigates away from this page.
+func (p *Page) Enqueue(task tasker.Task) error {
+	if err := p.app.Enqueue(task); err != nil {
+		return err
+	}
+	p.tasks = append(p.tasks, task.Name)
+	return nil
+}
+
+// Tear down the page, this is called when the user navigates away from it.
+//
+// Destroyer.OnLeave and SignalPageDestroyed are only run for pages which were rendered,
+// the functions added with OnDestroy release resources, and always run.
+func (p *Page) destroy() {
+	if destroyer, ok := p.handler.(Destroyer); ok && p.rendered {
+		destroyer.OnLeave(p)
+	}
+	for _, f := range p.onDestroy {
+		f(p)
+	}
+	for _, name := range p.tasks {
+		if err := p.app.Dequeue(name); err != nil && err != tasker.ErrNotFound {
+			p.app.LogErrorf("Error dequeuing task %s: %s", name, err)
+		}
+	}
+	p.onDestroy = nil
+	p.tasks = nil
+	if p.cancel != nil {
+		p.cancel()
+	}
+
+	if p.rendered {
+		p.app.signals.CreateOrSend(SignalPageDestroyed, p)
+	}
+}
+
+// BeforeLeave adds a navigation guard which will run before the user leaves this page.
+//
+// This is useful for warning the user about unsaved changes.
+func (p *Page) BeforeLeave(g ...NavigationGuard) {
+	p.leaveGuards = append(p.leaveGuards, g...)
+}
+
+// NamedResponse returns the response and error of a request made with HandleEndpoints.
+func (p *Page) NamedResponse(name string) (*craterhttp.Response, error) {
+	return p.Responses[name], p.ResponseErrors[name]
+}
+
+// App returns the application which rendered the page.
+func (p *Page) App() *App {
+	return p.app
+}
+
+// Path returns the path of the page.
+func (p *Page) Path() string {
+	return p.path
+}
+
+// Redirect the user to the given path.
+//
+// The page will not be rendered, the redirect happens after the page function returns.
+//
+// This is useful for short-circuiting in middleware.
+func (p *Page) Redirect(path string) {
+	p.redirect = path
 }
 
 func (p *Page) Clear() {
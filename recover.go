@@ -0,0 +1,142 @@
+package crater
+
+import (
+	"fmt"
+	"runtime/debug"
+
+	"github.com/Nigel2392/jsext/v2/errs"
+	"github.com/Nigel2392/jsext/v2/jse"
+	"github.com/Nigel2392/jsext/v2/state"
+)
+
+// ErrPagePanic is wrapped by the *PanicError which is set as Page.Err
+// when a page panicked, use errors.Is to check for it.
+var ErrPagePanic = errs.Error("page panicked")
+
+// PanicError is the error of a page which panicked while it was served.
+type PanicError struct {
+	// The path of the page.
+	Path string
+
+	// The value passed to panic.
+	Value any
+
+	// The stack trace of the goroutine which panicked.
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("%s: %s: %v", ErrPagePanic, e.Path, e.Value)
+}
+
+func (e *PanicError) Unwrap() error {
+	return ErrPagePanic
+}
+
+// The error page which is served when no error page is configured.
+var defaultErrorPage = ToPageFunc(func(p *Page) {
+	p.AppendChild(
+		jse.Heading(1, "Something went wrong"),
+		jse.P("An error occurred while loading this page."),
+	)
+})
+
+// WithErrorPage sets the page which is served when a page panics.
+//
+// Page.Err will be a *PanicError.
+func (a *App) WithErrorPage(h PageFunc) {
+	a.errorPage = h
+}
+
+// WithErrorPage sets the page which is served when a page of the default application panics.
+func WithErrorPage(h PageFunc) {
+	checkApp()
+	application.WithErrorPage(h)
+}
+
+// Recover from a panic while serving a page, and serve the error page in its place.
+//
+// SignalPageRendered is sent for the error page.
+//
+// The page is nil if the panic occurred before it was created, a new page is created for the navigation.
+//
+// This must be called by the deferred function which recovered.
+func (a *App) recoverPage(rec any, nav *Navigation, page *Page) {
+	var path = nav.To
+	if page != nil {
+		path = page.path
+	}
+	var err = &PanicError{
+		Path:  path,
+		Value: rec,
+		Stack: debug.Stack(),
+	}
+	a.LogErrorf("Recovered from panic while serving %s: %v\n%s", path, rec, err.Stack)
+	a.signals.CreateOrSend(SignalPagePanic, err)
+
+	if a.closed.Load() {
+		return
+	}
+
+	if page == nil {
+		if prev := a.page; prev != nil {
+			a.page = nil
+			prev.destroy()
+		}
+		var ctx, cancel = newPageContext(nav)
+		var canvas = jse.Div("crater-canvas")
+		page = &Page{
+			Canvas:    canvas,
+			Variables: nav.ToVariables,
+			Context:   ctx,
+			State:     state.New(canvas.MarshalJS()),
+			app:       a,
+			path:      path,
+			uri:       nav.request.uri,
+			cancel:    cancel,
+		}
+	}
+
+	var errorPage = a.errorPage
+	if errorPage == nil {
+		errorPage = defaultErrorPage
+	}
+	if page.handler == nil {
+		page.handler = errorPage
+	}
+
+	page.Clear()
+	page.Err = err
+	page.AfterRender = nil
+	page.redirect = ""
+
+	func() {
+		defer func() {
+			if rec := recover(); rec != nil {
+				a.LogErrorf("Recovered from panic while serving the error page for %s: %v", path, rec)
+			}
+		}()
+		errorPage.Serve(page)
+	}()
+
+	// The error page is not embedded, the embed function may be what panicked.
+	if a.page != page {
+		a.mount(page.Canvas)
+		a.page = page
+		page.rendered = true
+	}
+
+	a.signals.CreateOrSend(SignalPageRendered, page)
+}
+
+// Render the canvas onto the application's root element.
+func (a *App) mount(canvas *jse.Element) {
+	// If the node is a body element we cannot replace it, so we will just append the canvas.
+	if a.Element.Get("nodeName").String() == "BODY" || a.config.Flags.Has(F_APPEND_CANVAS) {
+		a.Element.InnerHTML("")
+		a.Element.AppendChild(canvas)
+	} else {
+		// Replace the application's root element with the canvas.
+		a.Element.Replace(canvas)
+	}
+}
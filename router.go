@@ -0,0 +1,320 @@
+package crater
+
+import (
+	"net/url"
+	"strings"
+	"sync"
+	"syscall/js"
+
+	"github.com/Nigel2392/jsext/v2/history"
+	"github.com/Nigel2392/mux"
+)
+
+// Links with this prefix are opened in a new window, instead of being handled by the application.
+const externalLinkPrefix = mux.RT_PREFIX_EXTERNAL
+
+// How a navigation changes the browser's history.
+type historyMode int
+
+const (
+	historyPush historyMode = iota
+	historyReplace
+	historyNone
+)
+
+// How a navigation was started by the router, this is passed to the page handler with each navigation.
+type navigationRequest struct {
+	// The path and query navigated to.
+	uri string
+
+	// How the history was changed for the navigation.
+	mode historyMode
+
+	// The number of history entries traversed, for navigations started by the back and forward buttons.
+	//
+	// This is zero if it is unknown.
+	delta int
+}
+
+// The handler of a page, the router passes the navigation request along with the variables.
+type pageHandler struct {
+	serve func(v mux.Variables, req navigationRequest)
+}
+
+// Serve the page for a navigation which was not started by the router.
+func (h *pageHandler) ServeHTTP(v mux.Variables) {
+	h.serve(v, navigationRequest{uri: variablesPath(v), mode: historyNone})
+}
+
+// The history state of an entry pushed by an application, the index identifies the entry.
+func historyState(index int) map[string]interface{} {
+	return map[string]interface{}{"crater_index": index}
+}
+
+// Returns the index of a history state, false if the entry was not pushed by an application.
+func historyStateIndex(state js.Value) (int, bool) {
+	if state.Type() != js.TypeObject {
+		return 0, false
+	}
+	var index = state.Get("crater_index")
+	if index.Type() != js.TypeNumber {
+		return 0, false
+	}
+	return index.Int(), true
+}
+
+var (
+	// The applications which are listening for link clicks and history changes.
+	listening   = make(map[*App]struct{})
+	listeningMu sync.Mutex
+)
+
+// Reports whether the application keeps track of its own path, instead of the browser's location.
+func (a *App) noHistory() bool {
+	return a.config.Flags.Has(F_NO_HISTORY)
+}
+
+// Add the link click and history listeners of the application, and navigate to the first page.
+//
+// Applications do not use mux's listeners, mux listens for clicks on the whole document and routes
+// on the browser's location, so applications sharing a page would handle each other's links.
+// An application only handles the links inside its root element, the mux is only used as the route table.
+//
+// The first page is the initial page URL if it is set, otherwise the current location.
+// Applications which do not use the history start at "/" if no initial page URL is set.
+func (a *App) listen() {
+	a.clickFunc = js.FuncOf(a.handleClick)
+	js.Global().Get("document").Call("addEventListener", "click", a.clickFunc)
+	if !a.noHistory() {
+		a.popStateFunc = js.FuncOf(a.handlePopState)
+		js.Global().Call("addEventListener", "popstate", a.popStateFunc)
+	}
+
+	listeningMu.Lock()
+	listening[a] = struct{}{}
+	listeningMu.Unlock()
+
+	// Mark the current entry, so the number of entries traversed is known when the user returns to it.
+	if !a.noHistory() {
+		if index, ok := historyStateIndex(history.State()); ok {
+			a.historyIndex = index
+		} else {
+			history.ReplaceState(historyState(a.historyIndex), "", js.Global().Get("location").Get("href").String())
+		}
+	}
+
+	var path = a.config.InitialPageURL
+	switch {
+	case path != "" && !a.noHistory():
+		a.navigate(path, historyReplace)
+	case path != "":
+		a.navigate(path, historyNone)
+	case a.noHistory():
+		a.navigate("/", historyNone)
+	default:
+		a.navigate(js.Global().Get("location").Get("href").String(), historyNone)
+	}
+}
+
+// Remove the link click and history listeners of the application.
+func (a *App) stopListening() {
+	listeningMu.Lock()
+	delete(listening, a)
+	listeningMu.Unlock()
+
+	if a.clickFunc.Truthy() {
+		js.Global().Get("document").Call("removeEventListener", "click", a.clickFunc)
+		a.clickFunc.Release()
+		a.clickFunc = js.Func{}
+	}
+	if a.popStateFunc.Truthy() {
+		js.Global().Call("removeEventListener", "popstate", a.popStateFunc)
+		a.popStateFunc.Release()
+		a.popStateFunc = js.Func{}
+	}
+}
+
+// Reports whether the element is inside the root element of the application.
+func (a *App) contains(e js.Value) bool {
+	return a.Element != nil && a.Element.Call("contains", e).Bool()
+}
+
+// Reports whether the application handles a click on the link.
+//
+// Links inside the root element of the application are handled by it.
+// Links outside of the root elements of all listening applications are handled by the default application.
+func (a *App) ownsLink(link js.Value) bool {
+	if a.contains(link) {
+		return true
+	}
+	if a != application {
+		return false
+	}
+	listeningMu.Lock()
+	defer listeningMu.Unlock()
+	for other := range listening {
+		if other != a && other.contains(link) {
+			return false
+		}
+	}
+	return true
+}
+
+// Reports whether the application handles a change of the browser's location to the path.
+//
+// An application handles the paths it has routes for,
+// the default application also handles the paths no other listening application has routes for.
+func (a *App) ownsPath(path string) bool {
+	if route, _ := a.Mux.Match(path); route != nil {
+		return true
+	}
+	if a != application {
+		return false
+	}
+	listeningMu.Lock()
+	defer listeningMu.Unlock()
+	for other := range listening {
+		if other == a || other.noHistory() {
+			continue
+		}
+		if route, _ := other.Mux.Match(path); route != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// Handle a click on the document, links owned by the application are navigated to.
+func (a *App) handleClick(this js.Value, args []js.Value) any {
+	if len(args) < 1 || args[0].Type() != js.TypeObject {
+		return nil
+	}
+	var event = args[0]
+	if event.Get("defaultPrevented").Bool() {
+		return nil
+	}
+
+	var link = event.Get("target")
+	for link.Truthy() && strings.ToUpper(link.Get("nodeName").String()) != "A" {
+		link = link.Get("parentNode")
+	}
+	if !link.Truthy() || !a.ownsLink(link) {
+		return nil
+	}
+
+	event.Call("preventDefault")
+
+	var href = link.Get("href").String()
+	if strings.HasPrefix(href, externalLinkPrefix) {
+		js.Global().Call("open", strings.TrimPrefix(href, externalLinkPrefix), "_blank")
+		return nil
+	}
+
+	var u, err = url.Parse(href)
+	if err != nil {
+		return nil
+	}
+	if u.Path == "" {
+		u.Path = "/"
+	}
+
+	// If the path is the same as the current path, do not navigate.
+	if u.Path == a.currentPath && !a.config.Flags.Has(F_CHANGE_PAGE_EACH_CLICK) {
+		return nil
+	}
+
+	a.navigate(u.RequestURI(), historyPush)
+	return nil
+}
+
+// Handle a change of the browser's location, the page for the location is rendered.
+func (a *App) handlePopState(this js.Value, args []js.Value) any {
+	var delta int
+	if len(args) > 0 && args[0].Type() == js.TypeObject {
+		if index, ok := historyStateIndex(args[0].Get("state")); ok {
+			delta = index - a.historyIndex
+			a.historyIndex = index
+		}
+	}
+
+	// The history was traversed back because a navigation was cancelled.
+	if a.skipPop {
+		a.skipPop = false
+		if redirect := a.popRedirect; redirect != "" {
+			a.popRedirect = ""
+			a.navigate(redirect, historyPush)
+		}
+		return nil
+	}
+
+	var href = js.Global().Get("location").Get("href").String()
+	var u, err = url.Parse(href)
+	if err != nil || !a.ownsPath(u.Path) {
+		return nil
+	}
+	a.dispatch(href, navigationRequest{mode: historyNone, delta: delta})
+	return nil
+}
+
+// Navigate to the path, the history is changed according to the mode.
+func (a *App) navigate(path string, mode historyMode) {
+	a.dispatch(path, navigationRequest{mode: mode})
+}
+
+// Run the handler of the route matching the path in a goroutine.
+//
+// If no route matches the path, the not found handler is run.
+// The current path and the history are changed either way, like the browser does for a missing page.
+// The history is left as is for applications which do not use it.
+func (a *App) dispatch(path string, req navigationRequest) {
+	var u, err = url.Parse(path)
+	if err != nil {
+		u = &url.URL{Path: path}
+	}
+	if u.Path == "" {
+		u.Path = "/"
+	}
+
+	req.uri = u.RequestURI()
+	a.currentPath = u.Path
+	if !a.noHistory() {
+		switch req.mode {
+		case historyPush:
+			a.historyIndex++
+			history.PushState(historyState(a.historyIndex), "", req.uri)
+		case historyReplace:
+			history.ReplaceState(historyState(a.historyIndex), "", req.uri)
+		}
+	}
+
+	var route, variables = a.Mux.Match(u.Path)
+	if route == nil {
+		if a.Mux.NotFoundHandler != nil {
+			go serveRoute(a.Mux.NotFoundHandler, mux.Variables{"path": {u.Path}}, req)
+		} else {
+			a.LogDebugf("No page found for path: %s", u.Path)
+		}
+		return
+	}
+
+	if variables == nil {
+		variables = make(mux.Variables)
+	}
+	for k, v := range u.Query() {
+		if len(v) > 0 {
+			variables["queryparam_"+k] = append(variables["queryparam_"+k], v...)
+		}
+	}
+	variables["path"] = append(variables["path"], u.Path)
+
+	go serveRoute(route.Handler, variables, req)
+}
+
+// Serve the handler, passing the navigation request along if it is a page handler.
+func serveRoute(h mux.Handler, v mux.Variables, req navigationRequest) {
+	if page, ok := h.(*pageHandler); ok {
+		page.serve(v, req)
+		return
+	}
+	h.ServeHTTP(v)
+}
@@ -0,0 +1,157 @@
+package crater
+
+import (
+	"context"
+	"errors"
+	"syscall/js"
+)
+
+// OnShutdown adds functions which will run when the application is shut down.
+//
+// The functions run in the reverse order they were added, like deferred calls,
+// before the application's own resources are released.
+func (a *App) OnShutdown(f ...func(ctx context.Context) error) {
+	a.onShutdown = append(a.onShutdown, f...)
+}
+
+// OnShutdown adds functions which will run when the default application is shut down, see App.OnShutdown.
+func OnShutdown(f ...func(ctx context.Context) error) {
+	checkApp()
+	application.OnShutdown(f...)
+}
+
+// Shutdown tears down the application.
+//
+// SignalShutdown is sent, and the OnShutdown functions are run. If the context is done,
+// the remaining functions are skipped. Then the link click and history listeners are removed,
+// the current page is destroyed, its tasks and the application's tasks are dequeued,
+// the sockets and event sources are closed, global functions are released and removed
+// from the javascript global scope, the messenger is removed and the root element is cleared.
+// Run returns nil.
+//
+// If the application is the default application, New can be called again afterwards.
+//
+// The errors returned by the OnShutdown functions and the tasks are joined.
+// Calling Shutdown more than once does nothing.
+func (a *App) Shutdown(ctx context.Context) error {
+	if !a.closed.CompareAndSwap(false, true) {
+		return nil
+	}
+
+	var errs []error
+	if err := a.signals.CreateOrSend(SignalShutdown, a); err != nil {
+		errs = append(errs, err)
+	}
+
+	for i := len(a.onShutdown) - 1; i >= 0; i-- {
+		if err := ctx.Err(); err != nil {
+			errs = append(errs, err)
+			break
+		}
+		if err := a.onShutdown[i](ctx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	a.onShutdown = nil
+
+	a.stopListening()
+
+	// The page which is still loading is destroyed once it was served.
+	if a.pending != nil {
+		a.pending.cancel()
+		a.pending = nil
+	}
+
+	// Destroying the page dequeues the tasks scoped to it, and cancels its context.
+	if a.page != nil {
+		a.page.destroy()
+		a.page = nil
+	}
+
+	// Pending navigations are ignored once the application is closed.
+	a.Mux.ResetRoutes()
+	a.Mux.NotFoundHandler = nil
+
+	if clearer, ok := a.Tasks.(TaskClearer); ok {
+		if err := clearer.DequeueAll(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	a.closeSockets()
+
+	for name, fn := range a.globalJSFuncs {
+		dataGlobal.Delete(name)
+		fn.Release()
+	}
+	for key := range a.globalKeys {
+		dataGlobal.Delete(key)
+	}
+	a.globalJSFuncs = make(map[string]js.Func)
+	a.globalKeys = make(map[string]struct{})
+	a.globalFuncs = make(map[string]func(args ...interface{}) Marshaller)
+
+	if a.Loader != nil {
+		a.Loader.Hide()
+		if remover, ok := a.Loader.(Remover); ok {
+			remover.Remove()
+		}
+	}
+	if remover, ok := a.Messenger.(Remover); ok {
+		remover.Remove()
+	}
+
+	a.Element.InnerHTML("")
+
+	if application == a {
+		application = nil
+	}
+
+	a.Exit(nil)
+	return errors.Join(errs...)
+}
+
+// Shutdown tears down the default application, see App.Shutdown.
+//
+// New can be called again afterwards.
+func Shutdown(ctx context.Context) error {
+	checkApp()
+	return application.Shutdown(ctx)
+}
+
+// Close the application's websockets and event sources, including those opened by pages.
+func (a *App) closeSockets() {
+	if a.Websocket != nil {
+		a.Websocket.Close(1000)
+		a.Websocket = nil
+	}
+	if a.ManagedSocket != nil {
+		a.ManagedSocket.Close()
+		a.ManagedSocket = nil
+	}
+	if a.EventSource != nil {
+		a.EventSource.Close()
+		a.EventSource = nil
+	}
+
+	a.socketsMut.Lock()
+	var named = a.sockets
+	a.sockets = make(map[string]*namedSock)
+	a.socketsMut.Unlock()
+	for _, s := range named {
+		s.sock.Close()
+	}
+
+	a.socksMut.Lock()
+	for _, sock := range a.socks {
+		if sock != nil && sock.IsOpen() {
+			sock.Close(1000)
+		}
+	}
+	for _, source := range a.eventSources {
+		source.Close()
+	}
+	a.socks = nil
+	a.eventSources = nil
+	a.socksMut.Unlock()
+}
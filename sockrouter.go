@@ -0,0 +1,472 @@
+package crater
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"syscall/js"
+	"time"
+
+	"github.com/Nigel2392/jsext/v2/encoding"
+	"github.com/Nigel2392/jsext/v2/errs"
+	"github.com/Nigel2392/jsext/v2/websocket"
+)
+
+var (
+	ErrRouterClosed   = errs.Error("socket router is closed")
+	ErrInvalidMessage = errs.Error("invalid socket message")
+)
+
+// Codec encodes and decodes the messages sent over a websocket by a SockRouter.
+//
+// A message is an envelope with a type, an optional correlation ID and error, and the data.
+type Codec interface {
+	// Marshal encodes the message's envelope with the data.
+	Marshal(m *SockMessage, data any) ([]byte, error)
+
+	// Unmarshal decodes the envelope of a message into m.
+	Unmarshal(raw []byte, m *SockMessage) error
+
+	// UnmarshalData decodes the data of a message into dst.
+	UnmarshalData(raw []byte, dst any) error
+}
+
+type jsonEnvelope struct {
+	Type  string `json:"type"`
+	ID    string `json:"id,omitempty"`
+	Error string `json:"error,omitempty"`
+	Data  any    `json:"data,omitempty"`
+}
+
+type jsonCodec struct{}
+
+func (jsonCodec) Marshal(m *SockMessage, data any) ([]byte, error) {
+	return encoding.EncodeJSON[[]byte](&jsonEnvelope{
+		Type:  m.Type,
+		ID:    m.ID,
+		Error: m.Error,
+		Data:  data,
+	})
+}
+
+func (jsonCodec) Unmarshal(raw []byte, m *SockMessage) error {
+	var envelope struct {
+		Type  string `json:"type"`
+		ID    string `json:"id"`
+		Error string `json:"error"`
+	}
+	if err := encoding.DecodeJSON(raw, &envelope); err != nil {
+		return err
+	}
+	m.Type = envelope.Type
+	m.ID = envelope.ID
+	m.Error = envelope.Error
+	return nil
+}
+
+func (jsonCodec) UnmarshalData(raw []byte, dst any) error {
+	return encoding.DecodeJSON(raw, &struct {
+		Data any `json:"data"`
+	}{Data: dst})
+}
+
+// JSONCodec encodes messages as JSON objects, for example:
+//
+//	{"type": "chat.message", "id": "1", "data": {"text": "Hello"}}
+var JSONCodec Codec = jsonCodec{}
+
+// SockMessage is a message received by a SockRouter.
+type SockMessage struct {
+	// The type of the message, used to route it to its handlers.
+	Type string
+
+	// The correlation ID of an RPC request and its response.
+	ID string
+
+	// The error returned by the server for an RPC request.
+	Error string
+
+	raw   []byte
+	codec Codec
+}
+
+// Decode the data of the message into dst.
+func (m *SockMessage) Decode(dst any) error {
+	return m.codec.UnmarshalData(m.raw, dst)
+}
+
+// Bytes returns the encoded message.
+func (m *SockMessage) Bytes() []byte {
+	return m.raw
+}
+
+// RPCError is returned by SockRouter.Call when the server responds with an error.
+type RPCError struct {
+	Type    string
+	Message string
+}
+
+func (e *RPCError) Error() string {
+	return e.Type + ": " + e.Message
+}
+
+// SockRouter routes the messages received over a websocket to typed handlers,
+// and makes request/response calls with correlation IDs.
+//
+// Create one with RouteSock or RouteManagedSock.
+type SockRouter struct {
+	// The default timeout for calls, if the context has no deadline.
+	//
+	// If zero, 30 seconds is used.
+	Timeout time.Duration
+
+	// The message types used to subscribe and unsubscribe from a topic.
+	//
+	// The data sent is {"topic": "<topic>"}, by default "subscribe" and "unsubscribe" are used.
+	SubscribeType   string
+	UnsubscribeType string
+
+	app    *App
+	codec  Codec
+	send   func([]byte) error
+	online func() bool
+
+	mu            sync.Mutex
+	handlerID     uint64
+	handlers      map[string]map[uint64]func(*SockMessage)
+	pending       map[string]chan *SockMessage
+	subscriptions map[string]int
+	closed        bool
+	nextID        uint64
+}
+
+// Create a new router which sends messages with send.
+//
+// Received messages must be passed to Dispatch, if codec is nil, JSONCodec is used.
+func NewSockRouter(send func(data []byte) error, codec Codec) *SockRouter {
+	if codec == nil {
+		codec = JSONCodec
+	}
+	return &SockRouter{
+		SubscribeType:   "subscribe",
+		UnsubscribeType: "unsubscribe",
+		codec:           codec,
+		send:            send,
+		handlers:        make(map[string]map[uint64]func(*SockMessage)),
+		pending:         make(map[string]chan *SockMessage),
+		subscriptions:   make(map[string]int),
+	}
+}
+
+// Route the messages received by a websocket, errors are logged by the default application.
+//
+// Subscriptions made before the socket is open are sent when it opens.
+func RouteSock(w *websocket.WebSocket, codec Codec) *SockRouter {
+	return routeSock(nil, w, codec)
+}
+
+// Route the messages received by a websocket, errors are logged by the application, see RouteSock.
+func (a *App) RouteSock(w *websocket.WebSocket, codec Codec) *SockRouter {
+	return routeSock(a, w, codec)
+}
+
+func routeSock(a *App, w *websocket.WebSocket, codec Codec) *SockRouter {
+	var r = NewSockRouter(w.SendBytes, codec)
+	r.app = a
+	r.online = w.IsOpen
+	w.OnMessage(func(w *websocket.WebSocket, e websocket.MessageEvent) {
+		r.dispatchEvent(e)
+	})
+	w.OnOpen(func(w *websocket.WebSocket, e websocket.MessageEvent) {
+		r.resubscribe()
+	})
+	return r
+}
+
+// Route the messages received by a managed websocket.
+//
+// Messages sent while disconnected are buffered by the socket,
+// subscriptions are renewed each time the socket reconnects.
+func RouteManagedSock(m *ManagedSocket, codec Codec) *SockRouter {
+	var r = NewSockRouter(m.Send, codec)
+	r.app = m.app
+	r.online = func() bool {
+		return m.State() == SockOpen
+	}
+	m.OnMessage(func(m *ManagedSocket, e websocket.MessageEvent) {
+		r.dispatchEvent(e)
+	})
+	m.OnOpen(func(m *ManagedSocket) {
+		r.resubscribe()
+	})
+	return r
+}
+
+// Decode and route a message.
+func (r *SockRouter) Dispatch(raw []byte) error {
+	var m = &SockMessage{
+		raw:   raw,
+		codec: r.codec,
+	}
+	if err := r.codec.Unmarshal(raw, m); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	if m.ID != "" {
+		if ch, ok := r.pending[m.ID]; ok {
+			delete(r.pending, m.ID)
+			r.mu.Unlock()
+			ch <- m
+			return nil
+		}
+	}
+	var handlers = make([]func(*SockMessage), 0, len(r.handlers[m.Type]))
+	for _, h := range r.handlers[m.Type] {
+		handlers = append(handlers, h)
+	}
+	r.mu.Unlock()
+
+	if len(handlers) == 0 {
+		r.logDebugf("No handlers for socket message of type %s", m.Type)
+	}
+	for _, h := range handlers {
+		h(m)
+	}
+	return nil
+}
+
+func (r *SockRouter) dispatchEvent(e websocket.MessageEvent) {
+	var data = e.Data()
+	var raw []byte
+	switch {
+	case data.Type() == js.TypeString:
+		raw = []byte(data.String())
+	case data.InstanceOf(js.Global().Get("ArrayBuffer")):
+		var arr = js.Global().Get("Uint8Array").New(data)
+		raw = make([]byte, arr.Length())
+		js.CopyBytesToGo(raw, arr)
+	default:
+		r.logErrorf("Error routing socket message: %s", ErrInvalidMessage)
+		return
+	}
+	if err := r.Dispatch(raw); err != nil {
+		r.logErrorf("Error routing socket message: %s", err)
+	}
+}
+
+// Handle adds a handler for messages of the given type.
+//
+// The returned function removes the handler.
+func (r *SockRouter) Handle(typ string, fn func(m *SockMessage)) (remove func()) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.handlerID++
+	var id = r.handlerID
+	if r.handlers[typ] == nil {
+		r.handlers[typ] = make(map[uint64]func(*SockMessage))
+	}
+	r.handlers[typ][id] = fn
+	return func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		delete(r.handlers[typ], id)
+		if len(r.handlers[typ]) == 0 {
+			delete(r.handlers, typ)
+		}
+	}
+}
+
+// OnMessage adds a handler for messages of the given type, the data is decoded into T.
+//
+// Messages which cannot be decoded are logged, and not passed to the handler.
+// The returned function removes the handler.
+func OnMessage[T any](r *SockRouter, typ string, fn func(v T)) (remove func()) {
+	return r.Handle(typ, func(m *SockMessage) {
+		var v T
+		if err := m.Decode(&v); err != nil {
+			r.logErrorf("Error decoding %s message: %s", typ, err)
+			return
+		}
+		fn(v)
+	})
+}
+
+// Send a message of the given type.
+func (r *SockRouter) Send(typ string, data any) error {
+	return r.sendMessage(&SockMessage{Type: typ}, data)
+}
+
+func (r *SockRouter) sendMessage(m *SockMessage, data any) error {
+	r.mu.Lock()
+	var closed = r.closed
+	r.mu.Unlock()
+	if closed {
+		return ErrRouterClosed
+	}
+	var raw, err = r.codec.Marshal(m, data)
+	if err != nil {
+		return err
+	}
+	return r.send(raw)
+}
+
+// Call sends a request of the given type, and waits for the response with the same correlation ID.
+//
+// The response data is decoded into dst if it is not nil.
+// If the server responds with an error, an *RPCError is returned.
+func (r *SockRouter) Call(ctx context.Context, typ string, data any, dst any) error {
+	if _, ok := ctx.Deadline(); !ok {
+		var timeout = r.Timeout
+		if timeout <= 0 {
+			timeout = 30 * time.Second
+		}
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	var ch = make(chan *SockMessage, 1)
+	r.mu.Lock()
+	if r.closed {
+		r.mu.Unlock()
+		return ErrRouterClosed
+	}
+	r.nextID++
+	var id = strconv.FormatUint(r.nextID, 10)
+	r.pending[id] = ch
+	r.mu.Unlock()
+
+	var forget = func() {
+		r.mu.Lock()
+		delete(r.pending, id)
+		r.mu.Unlock()
+	}
+
+	if err := r.sendMessage(&SockMessage{Type: typ, ID: id}, data); err != nil {
+		forget()
+		return err
+	}
+
+	select {
+	case m, ok := <-ch:
+		if !ok {
+			return ErrRouterClosed
+		}
+		if m.Error != "" {
+			return &RPCError{Type: typ, Message: m.Error}
+		}
+		if dst != nil {
+			return m.Decode(dst)
+		}
+		return nil
+	case <-ctx.Done():
+		forget()
+		return ctx.Err()
+	}
+}
+
+// Subscribe to a topic the server pushes messages for, the messages have the topic as their type.
+//
+// The subscribe message is only sent for the first subscriber of a topic,
+// and the unsubscribe message when the last subscriber is removed.
+// The returned function removes the subscription.
+func (r *SockRouter) Subscribe(topic string, fn func(m *SockMessage)) (unsubscribe func()) {
+	var remove = r.Handle(topic, fn)
+
+	r.mu.Lock()
+	r.subscriptions[topic]++
+	var first = r.subscriptions[topic] == 1
+	r.mu.Unlock()
+	if first && r.isOnline() {
+		if err := r.Send(r.SubscribeType, topicData{topic}); err != nil {
+			r.logErrorf("Error subscribing to %s: %s", topic, err)
+		}
+	}
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			remove()
+			r.mu.Lock()
+			r.subscriptions[topic]--
+			var last = r.subscriptions[topic] <= 0
+			if last {
+				delete(r.subscriptions, topic)
+			}
+			r.mu.Unlock()
+			if last && r.isOnline() {
+				if err := r.Send(r.UnsubscribeType, topicData{topic}); err != nil {
+					r.logErrorf("Error unsubscribing from %s: %s", topic, err)
+				}
+			}
+		})
+	}
+}
+
+// SubscribePage subscribes the page to a topic, the data is decoded into T.
+//
+// The subscription is removed when the user navigates away from the page.
+func SubscribePage[T any](p *Page, r *SockRouter, topic string, fn func(p *Page, v T)) {
+	var unsubscribe = r.Subscribe(topic, func(m *SockMessage) {
+		var v T
+		if err := m.Decode(&v); err != nil {
+			p.app.LogErrorf("Error decoding %s message: %s", topic, err)
+			return
+		}
+		fn(p, v)
+	})
+	p.OnDestroy(func(p *Page) {
+		unsubscribe()
+	})
+}
+
+type topicData struct {
+	Topic string `json:"topic"`
+}
+
+// Log an error with the router's application, or the default application if there is none.
+func (r *SockRouter) logErrorf(format string, v ...interface{}) {
+	if a := appOr(r.app); a != nil {
+		a.LogErrorf(format, v...)
+	}
+}
+
+// Log a debug message with the router's application, or the default application if there is none.
+func (r *SockRouter) logDebugf(format string, v ...interface{}) {
+	if a := appOr(r.app); a != nil {
+		a.LogDebugf(format, v...)
+	}
+}
+
+func (r *SockRouter) isOnline() bool {
+	return r.online == nil || r.online()
+}
+
+// Send the subscribe messages for all topics again, after the socket reconnected.
+func (r *SockRouter) resubscribe() {
+	r.mu.Lock()
+	var topics = make([]string, 0, len(r.subscriptions))
+	for topic := range r.subscriptions {
+		topics = append(topics, topic)
+	}
+	r.mu.Unlock()
+	for _, topic := range topics {
+		if err := r.Send(r.SubscribeType, topicData{topic}); err != nil {
+			r.logErrorf("Error subscribing to %s: %s", topic, err)
+		}
+	}
+}
+
+// Close the router, pending calls return ErrRouterClosed.
+//
+// The underlying socket is not closed.
+func (r *SockRouter) Close() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.closed = true
+	for id, ch := range r.pending {
+		close(ch)
+		delete(r.pending, id)
+	}
+}
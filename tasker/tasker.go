@@ -133,6 +133,17 @@ func (t *tasker) Dequeue(taskName string) error {
 	return ErrNotFound
 }
 
+// DequeueAll dequeues every task, the first error returned by an OnDequeue function is returned.
+func (t *tasker) DequeueAll() error {
+	var firstErr error
+	for name := range t.taskQueue {
+		if err := t.Dequeue(name); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
 type task struct {
 	T      *Task
 	ticker *time.Ticker
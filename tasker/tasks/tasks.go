@@ -9,6 +9,8 @@ import (
 )
 
 var (
+	// Requests with a status code >= 400 return a *craterhttp.HTTPError,
+	// which matches this error with errors.Is.
 	ErrRequestNotOK = errs.Error("request not ok")
 )
 
@@ -20,6 +22,10 @@ type HttpRequestOptions struct {
 	tasker.Task
 }
 
+// Create a task which makes a request, and passes the response to OnSuccess.
+//
+// If the server responds with a status code >= 400, the task returns a *craterhttp.HTTPError
+// with the status, headers and body of the response. Use errors.Is(err, ErrRequestNotOK) to check for it.
 func HttpRequest(option HttpRequestOptions) tasker.Task {
 	if option.Client == nil {
 		option.Client = craterhttp.DefaultClient
@@ -44,7 +50,7 @@ func HttpRequest(option HttpRequestOptions) tasker.Task {
 				return err
 			}
 			if resp.StatusCode >= 400 {
-				return ErrRequestNotOK
+				return craterhttp.NewHTTPError(resp)
 			}
 			if option.OnSuccess == nil {
 				return nil